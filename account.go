@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"egg_catcher2/api"
+)
+
+// readInput дописывает введённые символы к s и обрабатывает Backspace.
+func readInput(s string, limit int) string {
+	for _, r := range ebiten.AppendInputChars(nil) {
+		if len(s) < limit {
+			s += string(r)
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(s) > 0 {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+type AccountState struct {
+	backend         api.Backend
+	playerID        int
+	phase           string // "menu", "change" или "delete"
+	field           int    // Активное поле ввода
+	oldPassword     string
+	newPassword     string
+	confirmPassword string
+	changeButton    Button
+	deleteButton    Button
+	backButton      Button
+	submitButton    Button
+	logoutButton    Button
+	logoutAllButton Button
+	errorMsg        string
+	infoMsg         string
+	done            bool // Возврат в игру
+	deleted         bool // Аккаунт удалён
+	loggedOut       bool // Выход из аккаунта
+}
+
+func NewAccountState(backend api.Backend, playerID int) *AccountState {
+	return &AccountState{
+		backend:  backend,
+		playerID: playerID,
+		phase:    "menu",
+		changeButton: Button{
+			x:     screenWidth/3 - buttonWidth - 10,
+			y:     screenHeight/3 + 20,
+			w:     buttonWidth,
+			h:     buttonHeight,
+			label: "Change Password",
+		},
+		deleteButton: Button{
+			x:     screenWidth/3 + 10,
+			y:     screenHeight/3 + 20,
+			w:     buttonWidth,
+			h:     buttonHeight,
+			label: "Delete Account",
+		},
+		submitButton: Button{
+			x:     screenWidth/3 - buttonWidth - 10,
+			y:     screenHeight/3 + 80,
+			w:     buttonWidth,
+			h:     buttonHeight,
+			label: "Submit",
+		},
+		backButton: Button{
+			x:     screenWidth/3 + 10,
+			y:     screenHeight/3 + 80,
+			w:     buttonWidth,
+			h:     buttonHeight,
+			label: "Back",
+		},
+		logoutButton: Button{
+			x:     screenWidth/3 - buttonWidth - 10,
+			y:     screenHeight/3 + 140,
+			w:     buttonWidth,
+			h:     buttonHeight,
+			label: "Log Out",
+		},
+		logoutAllButton: Button{
+			x:     screenWidth/3 + 10,
+			y:     screenHeight/3 + 140,
+			w:     buttonWidth,
+			h:     buttonHeight,
+			label: "Log Out Everywhere",
+		},
+	}
+}
+
+func (s *AccountState) reset(phase string) {
+	s.phase = phase
+	s.field = 0
+	s.oldPassword = ""
+	s.newPassword = ""
+	s.confirmPassword = ""
+	s.errorMsg = ""
+}
+
+func (s *AccountState) submit() {
+	switch s.phase {
+	case "change":
+		if s.field < 2 {
+			s.field++
+			return
+		}
+		if s.newPassword != s.confirmPassword {
+			s.errorMsg = "Passwords do not match"
+			s.newPassword = ""
+			s.confirmPassword = ""
+			s.field = 1
+			return
+		}
+		if err := s.backend.ChangePassword(currentSessionToken, s.oldPassword, s.newPassword); err != nil {
+			s.reset("change")
+			s.errorMsg = err.Error()
+			return
+		}
+		s.reset("menu")
+		s.infoMsg = "Password changed"
+	case "delete":
+		if err := s.backend.DeleteAccount(currentSessionToken, s.oldPassword); err != nil {
+			s.reset("delete")
+			s.errorMsg = err.Error()
+			return
+		}
+		log.Printf("Deleted account with ID %d", s.playerID)
+		currentSessionToken = ""
+		removeLocalSession()
+		s.deleted = true
+	}
+}
+
+func (s *AccountState) Update() error {
+	switch s.phase {
+	case "change":
+		switch s.field {
+		case 0:
+			s.oldPassword = readInput(s.oldPassword, api.MaxPasswordLength)
+		case 1:
+			s.newPassword = readInput(s.newPassword, api.MaxPasswordLength)
+		case 2:
+			s.confirmPassword = readInput(s.confirmPassword, api.MaxPasswordLength)
+		}
+	case "delete":
+		s.oldPassword = readInput(s.oldPassword, api.MaxPasswordLength)
+	}
+
+	cx, cy := ebiten.CursorPosition()
+	mx, my := float64(cx), float64(cy)
+	s.changeButton.hovered = s.changeButton.IsInside(mx, my)
+	s.deleteButton.hovered = s.deleteButton.IsInside(mx, my)
+	s.submitButton.hovered = s.submitButton.IsInside(mx, my)
+	s.backButton.hovered = s.backButton.IsInside(mx, my)
+	s.logoutButton.hovered = s.logoutButton.IsInside(mx, my)
+	s.logoutAllButton.hovered = s.logoutAllButton.IsInside(mx, my)
+
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		if s.phase == "menu" && s.changeButton.hovered {
+			s.reset("change")
+			s.infoMsg = ""
+		} else if s.phase == "menu" && s.deleteButton.hovered {
+			s.reset("delete")
+			s.infoMsg = ""
+		} else if s.phase == "menu" && s.logoutButton.hovered {
+			logOut(s.backend)
+			s.loggedOut = true
+		} else if s.phase == "menu" && s.logoutAllButton.hovered {
+			if err := s.backend.LogoutAll(currentSessionToken); err != nil {
+				s.errorMsg = err.Error()
+			} else {
+				currentSessionToken = ""
+				removeLocalSession()
+				s.loggedOut = true
+			}
+		} else if s.phase != "menu" && s.submitButton.hovered {
+			s.submit()
+		} else if s.backButton.hovered {
+			if s.phase == "menu" {
+				s.done = true
+			} else {
+				s.reset("menu")
+			}
+		}
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) && s.phase != "menu" {
+		s.submit()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		if s.phase == "menu" {
+			s.done = true
+		} else {
+			s.reset("menu")
+		}
+	}
+	return nil
+}
+
+func (s *AccountState) Draw(screen *ebiten.Image) {
+	if imgBackgroundMenu != nil {
+		screen.DrawImage(imgBackgroundMenu, nil)
+	} else {
+		screen.Fill(color.RGBA{0, 128, 255, 255})
+	}
+
+	textImg := textLayer()
+	ebitenutil.DebugPrintAt(textImg, "Account", screenWidth/3-50, screenHeight/3-130)
+	switch s.phase {
+	case "change":
+		labels := []string{"Old password: ", "New password: ", "Confirm: "}
+		values := []string{s.oldPassword, s.newPassword, s.confirmPassword}
+		for i := range labels {
+			line := labels[i] + strings.Repeat("*", len(values[i]))
+			if i == s.field {
+				line += "_"
+			}
+			ebitenutil.DebugPrintAt(textImg, line, screenWidth/3-50, screenHeight/3-50+i*20)
+		}
+		ebitenutil.DebugPrintAt(textImg, fmt.Sprintf("At least %d characters, letters and digits", api.MinPasswordLength), screenWidth/3-100, screenHeight/3+5)
+	case "delete":
+		ebitenutil.DebugPrintAt(textImg, "This removes your account and all games", screenWidth/3-100, screenHeight/3-50)
+		ebitenutil.DebugPrintAt(textImg, "Password: "+strings.Repeat("*", len(s.oldPassword))+"_", screenWidth/3-50, screenHeight/3-20)
+	}
+	if s.errorMsg != "" {
+		ebitenutil.DebugPrintAt(textImg, "Error: "+s.errorMsg, screenWidth/3-100, screenHeight/3-100)
+	} else if s.infoMsg != "" {
+		ebitenutil.DebugPrintAt(textImg, s.infoMsg, screenWidth/3-100, screenHeight/3-100)
+	}
+
+	if s.phase == "menu" {
+		s.drawButton(textImg, &s.changeButton)
+		s.drawButton(textImg, &s.deleteButton)
+		s.drawButton(textImg, &s.logoutButton)
+		s.drawButton(textImg, &s.logoutAllButton)
+	} else {
+		s.drawButton(textImg, &s.submitButton)
+	}
+	s.drawButton(textImg, &s.backButton)
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(1.5, 1.5)
+	screen.DrawImage(textImg, op)
+}
+
+func (s *AccountState) drawButton(screen *ebiten.Image, b *Button) {
+	buttonColor := color.RGBA{0, 128, 255, 255}
+	if b.hovered {
+		buttonColor = color.RGBA{0, 192, 255, 255}
+	}
+	ebitenutil.DrawRect(screen, b.x, b.y, b.w, b.h, buttonColor)
+	ebitenutil.DebugPrintAt(screen, b.label, int(b.x+(b.w-float64(len(b.label)*7))/2), int(b.y+b.h/2))
+}
+
+func (s *AccountState) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return screenWidth, screenHeight
+}
@@ -0,0 +1,120 @@
+// Package achievement описывает достижения и проверяет их по событиям
+// симуляции. Один и тот же Tracker работает в игре, чтобы сразу показать
+// уведомление, и на сервере при проверке записи партии: засчитываются
+// только достижения, подтверждённые сервером.
+package achievement
+
+import "egg_catcher2/sim"
+
+const (
+	FirstBoss   = "first_boss"
+	BossSlayer  = "boss_slayer"
+	ComboMaster = "combo_50"
+	EggHoarder  = "eggs_1000"
+	Flawless    = "no_miss_level"
+	HardWin     = "win_hard"
+)
+
+const (
+	ComboGoal = 50   // Серия для ComboMaster
+	EggsGoal  = 1000 // Всего яиц для EggHoarder
+)
+
+// Achievement — описание достижения для интерфейса. ID хранится в базе и
+// не должен меняться.
+type Achievement struct {
+	ID          string
+	Name        string
+	Description string
+}
+
+// All перечисляет достижения в порядке показа в профиле.
+var All = []Achievement{
+	{ID: FirstBoss, Name: "Close Encounter", Description: "Reach the boss room"},
+	{ID: BossSlayer, Name: "Boss Slayer", Description: "Defeat the boss"},
+	{ID: ComboMaster, Name: "Combo Master", Description: "Catch 50 eggs in a row"},
+	{ID: EggHoarder, Name: "Egg Hoarder", Description: "Catch 1000 eggs in total"},
+	{ID: Flawless, Name: "Flawless", Description: "Clear a level without losing a life"},
+	{ID: HardWin, Name: "Hard Boiled", Description: "Defeat the boss on Hard"},
+}
+
+// ByID находит достижение по его ID.
+func ByID(id string) (Achievement, bool) {
+	for _, a := range All {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return Achievement{}, false
+}
+
+// Tracker следит за событиями партии одного игрока и замечает новые
+// достижения.
+type Tracker struct {
+	slot       int
+	eggsCaught int // С учётом прошлых партий
+	unlocked   map[string]bool
+	missed     bool // Игрок терял жизнь на текущем уровне
+}
+
+// NewTracker начинает слежение за игроком slot. eggsCaught — яйца из
+// прошлых партий, unlocked — уже полученные достижения, о которых не
+// нужно сообщать повторно.
+func NewTracker(slot, eggsCaught int, unlocked []string) *Tracker {
+	t := &Tracker{
+		slot:       slot,
+		eggsCaught: eggsCaught,
+		unlocked:   make(map[string]bool),
+	}
+	for _, id := range unlocked {
+		t.unlocked[id] = true
+	}
+	return t
+}
+
+// Handle проверяет событие партии и возвращает достижения, впервые
+// полученные благодаря ему.
+func (t *Tracker) Handle(e sim.Event) []Achievement {
+	if e.Slot >= 0 && e.Slot != t.slot {
+		return nil
+	}
+	var earned []Achievement
+	unlock := func(id string) {
+		if t.unlocked[id] {
+			return
+		}
+		t.unlocked[id] = true
+		a, _ := ByID(id)
+		earned = append(earned, a)
+	}
+
+	switch e.Type {
+	case sim.EventLifeLost:
+		t.missed = true
+	// Уровень заканчивается переходом на следующий или появлением босса
+	case sim.EventLevelUp, sim.EventBossEntered:
+		if !t.missed {
+			unlock(Flawless)
+		}
+		t.missed = false
+		if e.Type == sim.EventBossEntered {
+			unlock(FirstBoss)
+		}
+	case sim.EventGameOver:
+		if e.Won {
+			unlock(BossSlayer)
+			if e.Difficulty == sim.DifficultyHard {
+				unlock(HardWin)
+			}
+		}
+	case sim.EventEggCaught:
+		t.eggsCaught++
+		if e.Combo >= ComboGoal {
+			unlock(ComboMaster)
+		}
+		if t.eggsCaught >= EggsGoal {
+			unlock(EggHoarder)
+		}
+	}
+	return earned
+}
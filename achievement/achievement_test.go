@@ -0,0 +1,63 @@
+package achievement
+
+import (
+	"slices"
+	"testing"
+
+	"egg_catcher2/sim"
+)
+
+func earnedIDs(t *Tracker, events ...sim.Event) []string {
+	var ids []string
+	for _, e := range events {
+		for _, a := range t.Handle(e) {
+			ids = append(ids, a.ID)
+		}
+	}
+	return ids
+}
+
+func TestBossWinUnlocksByDifficulty(t *testing.T) {
+	for _, tc := range []struct {
+		difficulty sim.Difficulty
+		want       []string
+	}{
+		{sim.DifficultyNormal, []string{BossSlayer}},
+		{sim.DifficultyHard, []string{BossSlayer, HardWin}},
+	} {
+		got := earnedIDs(NewTracker(0, 0, nil), sim.Event{Type: sim.EventGameOver, Slot: -1, Won: true, Difficulty: tc.difficulty})
+		if !slices.Equal(got, tc.want) {
+			t.Fatalf("%v: earned %v, want %v", tc.difficulty, got, tc.want)
+		}
+	}
+	if got := earnedIDs(NewTracker(0, 0, nil), sim.Event{Type: sim.EventGameOver, Slot: -1, Difficulty: sim.DifficultyHard}); len(got) != 0 {
+		t.Fatalf("loss on Hard earned %v", got)
+	}
+}
+
+func TestUnlockedAchievementsAreNotRepeated(t *testing.T) {
+	tr := NewTracker(0, 0, []string{BossSlayer})
+	got := earnedIDs(tr, sim.Event{Type: sim.EventGameOver, Slot: -1, Won: true, Difficulty: sim.DifficultyHard})
+	if len(got) != 1 || got[0] != HardWin {
+		t.Fatalf("earned %v, want only %s", got, HardWin)
+	}
+}
+
+// BossSlayer должен быть достижим в настоящей партии: автопилот доходит
+// до босса и побеждает его.
+func TestBossSlayerReachableInGame(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		w := sim.NewWorld(seed)
+		tr := NewTracker(0, 0, nil)
+		for !w.Over() {
+			for _, e := range w.Step(w.Autopilot(0)) {
+				for _, a := range tr.Handle(e) {
+					if a.ID == BossSlayer {
+						return
+					}
+				}
+			}
+		}
+	}
+	t.Fatal("no autopilot game defeated the boss")
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"image/color"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"egg_catcher2/achievement"
+	"egg_catcher2/sim"
+)
+
+const (
+	toastFrames = 4 * sim.TicksPerSecond // Сколько висит уведомление
+	toastWidth  = 250
+	toastHeight = 38
+)
+
+// toast — уведомление о полученном достижении.
+type toast struct {
+	achievement achievement.Achievement
+	ttl         int
+}
+
+func loadAchievements(g *Game) {
+	if backend == nil {
+		return
+	}
+	progress, err := backend.Achievements(currentSessionToken)
+	if err != nil {
+		log.Printf("Error loading achievements for player ID %d: %v", g.playerID, err)
+		return
+	}
+	g.progress = progress
+	g.trackAchievements(0)
+}
+
+// trackAchievements начинает следить за достижениями игрока slot. Сами
+// достижения засчитывает сервер по записи партии, здесь они нужны только
+// для уведомлений.
+func (g *Game) trackAchievements(slot int) {
+	unlocked := make([]string, 0, len(g.progress.Unlocked))
+	for _, a := range g.progress.Unlocked {
+		unlocked = append(unlocked, a.ID)
+	}
+	g.achievements = achievement.NewTracker(slot, g.progress.EggsCaught, unlocked)
+}
+
+func (g *Game) subscribeAchievements(bus *sim.Bus) {
+	bus.SubscribeAll(func(e sim.Event) {
+		if g.achievements == nil {
+			return
+		}
+		for _, a := range g.achievements.Handle(e) {
+			log.Printf("Achievement unlocked: %s", a.Name)
+			g.toasts = append(g.toasts, toast{achievement: a, ttl: toastFrames})
+		}
+	})
+}
+
+func (g *Game) updateToasts() {
+	alive := g.toasts[:0]
+	for _, t := range g.toasts {
+		t.ttl--
+		if t.ttl > 0 {
+			alive = append(alive, t)
+		}
+	}
+	g.toasts = alive
+}
+
+// drawToasts выводит уведомления стопкой в правом верхнем углу.
+func (g *Game) drawToasts(screen *ebiten.Image) {
+	x := float64(screenWidth - toastWidth - 10)
+	for i, t := range g.toasts {
+		y := float64(10 + i*(toastHeight+6))
+		fillRect(screen, x, y, toastWidth, toastHeight, color.RGBA{40, 40, 40, 220})
+		fillRect(screen, x, y, 4, toastHeight, color.RGBA{255, 200, 0, 255})
+		g.text = append(append(g.text[:0], "Achievement: "...), t.achievement.Name...)
+		drawTextBytes(screen, g.text, x+10, y+3, 1)
+		ebitenutil.DebugPrintAt(screen, t.achievement.Description, int(x)+10, int(y)+19)
+	}
+}
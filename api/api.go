@@ -0,0 +1,147 @@
+// Package api описывает HTTP/JSON протокол между игрой и игровым сервером.
+package api
+
+import "time"
+
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 20
+)
+
+type Player struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	HighScore int    `json:"high_score"`
+}
+
+type Session struct {
+	Token  string `json:"token"`
+	Player Player `json:"player"`
+}
+
+type Credentials struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Remember bool   `json:"remember"`
+}
+
+type PasswordChange struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+type PasswordConfirm struct {
+	Password string `json:"password"`
+}
+
+// GameTicket выдаётся сервером перед партией. Seed партии складывается
+// из билетов всех её игроков (см. sim.CombineSeeds), а результат по
+// билету принимается один раз.
+type GameTicket struct {
+	Nonce int64 `json:"nonce"`
+	Seed  int64 `json:"seed"`
+}
+
+// GameStart запрашивает билет. С Ghost билет выдаётся на seed лучшего
+// забега, чтобы сыграть против его призрака.
+type GameStart struct {
+	Ghost bool `json:"ghost,omitempty"`
+}
+
+// GameResult отправляется после партии. Replay — закодированная
+// sim.Recording; сервер проигрывает её и сверяет счёт. В партии на двоих
+// каждый игрок отправляет ту же запись со своим номером Slot. Tickets —
+// Nonce билетов всех игроков по номерам; свой билет игрок тратит при
+// отправке.
+type GameResult struct {
+	Score     int     `json:"score"`
+	Lives     int     `json:"lives"`
+	BestCombo int     `json:"best_combo"`
+	Replay    []byte  `json:"replay"`
+	Slot      int     `json:"slot,omitempty"`
+	Tickets   []int64 `json:"tickets,omitempty"`
+}
+
+// BestRun — запись партии, установившей личный рекорд.
+type BestRun struct {
+	Score  int    `json:"score"`
+	Replay []byte `json:"replay"`
+}
+
+// DailyChallenge выдаётся при старте ежедневного испытания: у всех
+// игроков в этот день одинаковый Seed.
+type DailyChallenge struct {
+	Day  string `json:"day"`
+	Seed int64  `json:"seed"`
+}
+
+type DailyScore struct {
+	Name  string `json:"name"`
+	Score int    `json:"score"`
+}
+
+// Achievement — полученное достижение; описания достижений хранятся в
+// пакете achievement.
+type Achievement struct {
+	ID         string    `json:"id"`
+	UnlockedAt time.Time `json:"unlocked_at"`
+}
+
+// Achievements — прогресс игрока: полученные достижения и число яиц,
+// пойманных во всех партиях.
+type Achievements struct {
+	Unlocked   []Achievement `json:"unlocked"`
+	EggsCaught int           `json:"eggs_caught"`
+}
+
+// PlayerStats — статистика по всем партиям игрока.
+type PlayerStats struct {
+	Games        int     `json:"games"`
+	AverageScore float64 `json:"average_score"`
+	MedianScore  float64 `json:"median_score"`
+	EggsCaught   int     `json:"eggs_caught"`
+	BestLevel    int     `json:"best_level"`
+	BossFights   int     `json:"boss_fights"`
+	BossWins     int     `json:"boss_wins"`
+	RecentScores []int   `json:"recent_scores"` // От старых партий к новым
+}
+
+// Campaign — прогресс кампании: последний пройденный этап, 0 — ни
+// одного. Открыты все этапы до Completed+1 включительно.
+type Campaign struct {
+	Completed int `json:"completed"`
+}
+
+// Error передаётся клиенту в теле ответа с кодом Status.
+type Error struct {
+	Status     int    `json:"-"`
+	Message    string `json:"error"`
+	RetryAfter int    `json:"retry_after,omitempty"` // Секунды до следующей попытки входа
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Backend — всё, что игре нужно от сервера. Токен выдаётся при входе
+// и передаётся в каждом запросе, требующем авторизации.
+type Backend interface {
+	Register(name, password string, remember bool) (Session, error)
+	Login(name, password string, remember bool) (Session, error)
+	Profile(token string) (Player, error)
+	Logout(token string) error
+	LogoutAll(token string) error
+	ChangePassword(token, oldPassword, newPassword string) error
+	DeleteAccount(token, password string) error
+	StartGame(token string, ghost bool) (GameTicket, error)
+	SubmitGame(token string, result GameResult) (Player, error)
+	BestRun(token string) (BestRun, error)
+	Achievements(token string) (Achievements, error)
+	Stats(token string) (PlayerStats, error)
+	Campaign(token string) (Campaign, error)
+	StartDaily(token string) (DailyChallenge, error)
+	SubmitDaily(token string, result GameResult) error
+	// DailyLeaderboard с пустым day возвращает таблицу за сегодня.
+	DailyLeaderboard(day string, limit int) ([]DailyScore, error)
+	Leaderboard(limit int) ([]Player, error)
+}
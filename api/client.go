@@ -0,0 +1,162 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *Client) do(method, path, token string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach server: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		apiErr := &Error{Status: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = fmt.Sprintf("server error: %s", resp.Status)
+		}
+		return apiErr
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+	return nil
+}
+
+func (c *Client) Register(name, password string, remember bool) (Session, error) {
+	var s Session
+	err := c.do(http.MethodPost, "/api/register", "", Credentials{Name: name, Password: password, Remember: remember}, &s)
+	return s, err
+}
+
+func (c *Client) Login(name, password string, remember bool) (Session, error) {
+	var s Session
+	err := c.do(http.MethodPost, "/api/login", "", Credentials{Name: name, Password: password, Remember: remember}, &s)
+	return s, err
+}
+
+func (c *Client) Profile(token string) (Player, error) {
+	var p Player
+	err := c.do(http.MethodGet, "/api/profile", token, nil, &p)
+	return p, err
+}
+
+func (c *Client) Logout(token string) error {
+	return c.do(http.MethodPost, "/api/logout", token, nil, nil)
+}
+
+func (c *Client) LogoutAll(token string) error {
+	return c.do(http.MethodPost, "/api/logout-all", token, nil, nil)
+}
+
+func (c *Client) ChangePassword(token, oldPassword, newPassword string) error {
+	return c.do(http.MethodPost, "/api/password", token, PasswordChange{OldPassword: oldPassword, NewPassword: newPassword}, nil)
+}
+
+func (c *Client) DeleteAccount(token, password string) error {
+	return c.do(http.MethodPost, "/api/account/delete", token, PasswordConfirm{Password: password}, nil)
+}
+
+func (c *Client) StartGame(token string, ghost bool) (GameTicket, error) {
+	var t GameTicket
+	err := c.do(http.MethodPost, "/api/games/start", token, GameStart{Ghost: ghost}, &t)
+	return t, err
+}
+
+func (c *Client) SubmitGame(token string, result GameResult) (Player, error) {
+	var p Player
+	err := c.do(http.MethodPost, "/api/games", token, result, &p)
+	return p, err
+}
+
+func (c *Client) BestRun(token string) (BestRun, error) {
+	var run BestRun
+	err := c.do(http.MethodGet, "/api/best-run", token, nil, &run)
+	return run, err
+}
+
+func (c *Client) Achievements(token string) (Achievements, error) {
+	var a Achievements
+	err := c.do(http.MethodGet, "/api/achievements", token, nil, &a)
+	return a, err
+}
+
+func (c *Client) Stats(token string) (PlayerStats, error) {
+	var st PlayerStats
+	err := c.do(http.MethodGet, "/api/stats", token, nil, &st)
+	return st, err
+}
+
+func (c *Client) Campaign(token string) (Campaign, error) {
+	var campaign Campaign
+	err := c.do(http.MethodGet, "/api/campaign", token, nil, &campaign)
+	return campaign, err
+}
+
+func (c *Client) StartDaily(token string) (DailyChallenge, error) {
+	var daily DailyChallenge
+	err := c.do(http.MethodPost, "/api/daily/start", token, nil, &daily)
+	return daily, err
+}
+
+func (c *Client) SubmitDaily(token string, result GameResult) error {
+	return c.do(http.MethodPost, "/api/daily/submit", token, result, nil)
+}
+
+func (c *Client) DailyLeaderboard(day string, limit int) ([]DailyScore, error) {
+	var leaderboard []DailyScore
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	if day != "" {
+		query.Set("day", day)
+	}
+	err := c.do(http.MethodGet, "/api/daily/leaderboard?"+query.Encode(), "", nil, &leaderboard)
+	return leaderboard, err
+}
+
+func (c *Client) Leaderboard(limit int) ([]Player, error) {
+	var leaderboard []Player
+	err := c.do(http.MethodGet, fmt.Sprintf("/api/leaderboard?limit=%d", limit), "", nil, &leaderboard)
+	return leaderboard, err
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+)
+
+const (
+	manifestPath   = "assets.json"
+	reloadInterval = time.Second // Как часто режим разработки проверяет файлы
+)
+
+// assetEntry — картинка или звук из манифеста. Fallback используется,
+// если нет основного файла.
+type assetEntry struct {
+	Name     string  `json:"name"`
+	Path     string  `json:"path"`
+	Fallback string  `json:"fallback,omitempty"`
+	Volume   float64 `json:"volume,omitempty"` // Только для звуков, 0 — обычная громкость
+
+	inherited bool // Взят из манифеста классической темы, см. inherit
+}
+
+// assetManifest перечисляет все ресурсы игры, у темы — дополненные
+// ресурсами классической. Без любого из них игра работает: Draw рисует
+// вместо картинки цветной прямоугольник, а звук просто не играет.
+type assetManifest struct {
+	Name    string       `json:"name,omitempty"` // Название темы для экрана профиля
+	Images  []assetEntry `json:"images"`
+	Sprites string       `json:"sprites"` // Описание листов кадров, см. sprites.go
+	Sounds  []assetEntry `json:"sounds"`
+
+	spritesInherited bool
+}
+
+// imageGlobals связывает картинки манифеста с переменными, которые
+// рисует Draw.
+var imageGlobals = map[string]**ebiten.Image{
+	"background_menu": &imgBackgroundMenu,
+	"background_main": &imgBackgroundMain,
+	"fake_egg":        &imgFakeEgg,
+	"white_egg":       &imgWhiteEgg,
+	"gold_egg":        &imgGoldEgg,
+	"heart_full":      &imgHeart1,
+	"heart_empty":     &imgHeart2,
+	"boss_background": &imgBossBackground,
+	"boss_health_bar": &imgBossHealthBar,
+	"boss_hit":        &imgBossHit,
+}
+
+var sheetGlobals = map[string]**spriteSheet{
+	"wolf":     &sheetWolf,
+	"hen":      &sheetHen,
+	"boss_ufo": &sheetUfo,
+}
+
+// soundNames — звуки, которые ищет игра.
+var soundNames = []string{"music", "lose_heart", "gain_heart", "score_heart", "boss_music", "boss_hit"}
+
+// resources загружает ресурсы по манифесту из встроенных файлов, каталога
+// или архива темы. В режиме разработки изменённые файлы каталога
+// перезагружаются на лету.
+type resources struct {
+	fsys   fs.FS
+	base   fs.FS     // Файлы классической темы, nil у неё самой
+	closer io.Closer // Открытый архив темы
+
+	manifest  assetManifest
+	images    map[string]*ebiten.Image
+	sheets    map[string]*spriteSheet
+	sounds    map[string]*audio.Player
+	modTimes  map[assetFile]time.Time // Время изменения загруженных файлов
+	hotReload bool
+	checked   time.Time
+}
+
+// assetFile — файл ресурса вместе с файлами темы, в которых он лежит.
+type assetFile struct {
+	fsys fs.FS
+	path string
+}
+
+// assets — ресурсы игры, загружаются в main.
+var assets *resources
+
+// readManifest читает манифест path из fsys. Если в каталоге или архиве
+// нет манифеста, файлы в нём ищутся по путям встроенного.
+func readManifest(fsys fs.FS, path string) (assetManifest, error) {
+	var m assetManifest
+	data, err := fs.ReadFile(fsys, path)
+	if errors.Is(err, fs.ErrNotExist) && fsys != fs.FS(assetFiles) {
+		log.Printf("No %s, using the built-in manifest", path)
+		data, err = assetFiles.ReadFile(manifestPath)
+	}
+	if err != nil {
+		return m, fmt.Errorf("failed to read asset manifest: %v", err)
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("failed to parse asset manifest: %v", err)
+	}
+	return m, nil
+}
+
+// loadResources читает манифест manifest из fsys, сообщает о недостающих
+// файлах и загружает всё, что есть. Если задан base, манифест может
+// перечислять не все ресурсы: остальные берутся из манифеста и файлов
+// классической темы в base.
+func loadResources(fsys, base fs.FS, manifest string, hotReload bool) (*resources, error) {
+	r := &resources{
+		fsys:      fsys,
+		base:      base,
+		images:    make(map[string]*ebiten.Image),
+		sheets:    make(map[string]*spriteSheet),
+		sounds:    make(map[string]*audio.Player),
+		modTimes:  make(map[assetFile]time.Time),
+		hotReload: hotReload,
+	}
+	var err error
+	if r.manifest, err = readManifest(fsys, manifest); err != nil {
+		return nil, err
+	}
+	if base != nil {
+		classic, err := readManifest(base, manifestPath)
+		if err != nil {
+			return nil, err
+		}
+		r.manifest.inherit(classic)
+	}
+	if err := r.manifest.validate(); err != nil {
+		return nil, fmt.Errorf("invalid asset manifest: %v", err)
+	}
+	if missing := r.report(); missing > 0 {
+		log.Printf("%d asset files are missing, the game will use placeholders", missing)
+	}
+	r.refresh()
+	return r, nil
+}
+
+// validate проверяет, что у ресурсов есть имена и пути, имена не
+// повторяются и перечислены все ресурсы, которые ищет игра.
+func (m *assetManifest) validate() error {
+	for _, list := range [][]assetEntry{m.Images, m.Sounds} {
+		seen := make(map[string]bool)
+		for _, e := range list {
+			if e.Name == "" || e.Path == "" {
+				return fmt.Errorf("asset %q has no name or path", e.Name+e.Path)
+			}
+			if seen[e.Name] {
+				return fmt.Errorf("asset %q is listed twice", e.Name)
+			}
+			seen[e.Name] = true
+		}
+	}
+	if m.Sprites == "" {
+		return fmt.Errorf("sprite sheet description is not set")
+	}
+	for name := range imageGlobals {
+		if _, ok := m.entry(m.Images, name); !ok {
+			return fmt.Errorf("image %q is not listed", name)
+		}
+	}
+	for _, name := range soundNames {
+		if _, ok := m.entry(m.Sounds, name); !ok {
+			return fmt.Errorf("sound %q is not listed", name)
+		}
+	}
+	return nil
+}
+
+// inherit дополняет манифест темы ресурсами классической темы, которых
+// в теме нет, и пишет в лог каждый из них.
+func (m *assetManifest) inherit(classic assetManifest) {
+	add := func(list []assetEntry, from []assetEntry) []assetEntry {
+		for _, e := range from {
+			if _, ok := m.entry(list, e.Name); !ok {
+				log.Printf("Asset %q is not in the theme, using classic %s", e.Name, e.Path)
+				e.inherited = true
+				list = append(list, e)
+			}
+		}
+		return list
+	}
+	m.Images = add(m.Images, classic.Images)
+	m.Sounds = add(m.Sounds, classic.Sounds)
+	if m.Sprites == "" {
+		log.Printf("Sprite sheets are not in the theme, using classic %s", classic.Sprites)
+		m.Sprites = classic.Sprites
+		m.spritesInherited = true
+	}
+}
+
+func (m *assetManifest) entry(list []assetEntry, name string) (assetEntry, bool) {
+	for _, e := range list {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return assetEntry{}, false
+}
+
+// files возвращает файлы, в которых лежит ресурс: унаследованные
+// ресурсы читаются из файлов классической темы.
+func (r *resources) files(inherited bool) fs.FS {
+	if inherited {
+		return r.base
+	}
+	return r.fsys
+}
+
+func exists(fsys fs.FS, path string) bool {
+	_, err := fs.Stat(fsys, path)
+	return err == nil
+}
+
+// resolve возвращает файл ресурса: основной, запасной или "", если нет
+// ни того ни другого.
+func (r *resources) resolve(e assetEntry) string {
+	fsys := r.files(e.inherited)
+	if exists(fsys, e.Path) {
+		return e.Path
+	}
+	if e.Fallback != "" && exists(fsys, e.Fallback) {
+		return e.Fallback
+	}
+	return ""
+}
+
+// report пишет в лог каждый недостающий файл манифеста и возвращает их
+// число.
+func (r *resources) report() int {
+	missing := 0
+	for _, e := range append(append([]assetEntry(nil), r.manifest.Images...), r.manifest.Sounds...) {
+		fsys := r.files(e.inherited)
+		if exists(fsys, e.Path) {
+			continue
+		}
+		missing++
+		switch {
+		case e.Fallback == "":
+			log.Printf("Asset %q: %s is missing", e.Name, e.Path)
+		case exists(fsys, e.Fallback):
+			log.Printf("Asset %q: %s is missing, using fallback %s", e.Name, e.Path, e.Fallback)
+		default:
+			log.Printf("Asset %q: %s and its fallback %s are missing", e.Name, e.Path, e.Fallback)
+		}
+	}
+	if !exists(r.files(r.manifest.spritesInherited), r.manifest.Sprites) {
+		missing++
+		log.Printf("Sprite sheets: %s is missing", r.manifest.Sprites)
+	}
+	return missing
+}
+
+// changed запоминает время изменения файла и сообщает, изменился ли он
+// с прошлой проверки. Ещё не загруженный файл считается изменённым.
+func (r *resources) changed(fsys fs.FS, path string) bool {
+	if path == "" {
+		return false
+	}
+	info, err := fs.Stat(fsys, path)
+	if err != nil {
+		return false
+	}
+	file := assetFile{fsys, path}
+	if t, ok := r.modTimes[file]; ok && t.Equal(info.ModTime()) {
+		return false
+	}
+	r.modTimes[file] = info.ModTime()
+	return true
+}
+
+// refresh загружает новые и изменённые файлы и обновляет глобальные
+// картинки и звуки. Возвращает true, если поменялись звуки: их плееры
+// хранит и игра, см. GameWrapper.applySounds.
+func (r *resources) refresh() (soundsChanged bool) {
+	for _, e := range r.manifest.Images {
+		fsys, path := r.files(e.inherited), r.resolve(e)
+		if !r.changed(fsys, path) {
+			continue
+		}
+		img, err := loadImage(fsys, path)
+		if err != nil {
+			log.Printf("Error loading image %q: %v", e.Name, err)
+			continue
+		}
+		r.images[e.Name] = img
+	}
+
+	spriteFiles := r.files(r.manifest.spritesInherited)
+	reloadSheets := r.changed(spriteFiles, r.manifest.Sprites)
+	for _, sheet := range r.sheets {
+		reloadSheets = r.changed(spriteFiles, sheet.file) || reloadSheets
+	}
+	if reloadSheets {
+		sheets, err := loadSprites(spriteFiles, r.manifest.Sprites)
+		if err != nil {
+			log.Printf("Error loading sprites: %v", err)
+		} else {
+			r.sheets = sheets
+			for _, sheet := range sheets {
+				r.changed(spriteFiles, sheet.file)
+			}
+		}
+	}
+
+	for _, e := range r.manifest.Sounds {
+		fsys, path := r.files(e.inherited), r.resolve(e)
+		if !r.changed(fsys, path) {
+			continue
+		}
+		p, err := loadAudio(fsys, path)
+		if err != nil {
+			log.Printf("Error loading sound %q: %v", e.Name, err)
+			continue
+		}
+		if e.Volume > 0 {
+			p.SetVolume(e.Volume)
+		}
+		if old := r.sounds[e.Name]; old != nil {
+			if old.IsPlaying() {
+				p.Play()
+			}
+			if err := old.Close(); err != nil {
+				log.Printf("Error closing sound %q: %v", e.Name, err)
+			}
+		}
+		r.sounds[e.Name] = p
+		soundsChanged = true
+	}
+	r.apply()
+	return soundsChanged
+}
+
+// apply раздаёт загруженные ресурсы глобальным переменным.
+func (r *resources) apply() {
+	for name, img := range imageGlobals {
+		*img = r.images[name]
+	}
+	for name, sheet := range sheetGlobals {
+		// Аниматоры держат указатель на лист, поэтому новый лист
+		// копируется в старый, а вместо пропавшего остаётся пустой
+		switch {
+		case *sheet == nil:
+			*sheet = r.sheets[name]
+		case r.sheets[name] != nil:
+			**sheet = *r.sheets[name]
+		default:
+			**sheet = spriteSheet{}
+		}
+	}
+	player = r.sounds["music"]
+	bossMusic = r.sounds["boss_music"]
+	bossHitEffect = r.sounds["boss_hit"]
+}
+
+// close закрывает звуки и архив ресурсов, которые больше не нужны.
+// Картинки освободит сборщик мусора, когда их перестанут рисовать.
+func (r *resources) close() {
+	for name, p := range r.sounds {
+		if err := p.Close(); err != nil {
+			log.Printf("Error closing sound %q: %v", name, err)
+		}
+	}
+	if r.closer != nil {
+		if err := r.closer.Close(); err != nil {
+			log.Printf("Error closing theme archive: %v", err)
+		}
+	}
+}
+
+// poll в режиме разработки раз в reloadInterval перезагружает изменённые
+// файлы.
+func (r *resources) poll() (soundsChanged bool) {
+	if !r.hotReload || time.Since(r.checked) < reloadInterval {
+		return false
+	}
+	r.checked = time.Now()
+	return r.refresh()
+}
+
+// applySounds раздаёт звуки из ресурсов обёртке и текущей игре: после
+// перезагрузки или смены темы старые плееры закрыты.
+func (w *GameWrapper) applySounds() {
+	w.assets = assets
+	w.loseHeartPlayer = assets.sounds["lose_heart"]
+	w.gainHeartPlayer = assets.sounds["gain_heart"]
+	w.scoreHeartPlayer = assets.sounds["score_heart"]
+	w.bossMusic = assets.sounds["boss_music"]
+	w.bossHitEffect = assets.sounds["boss_hit"]
+	if g := w.game; g != nil {
+		g.loseHeartPlayer = w.loseHeartPlayer
+		g.gainHeartPlayer = w.gainHeartPlayer
+		g.scoreHeartPlayer = w.scoreHeartPlayer
+		g.bossMusic = w.bossMusic
+		g.bossHitEffect = w.bossHitEffect
+	}
+	applyMusicVolume(w.game != nil && w.game.isPaused)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+// partialManifest — тема, в которой есть только своя картинка яйца.
+const partialManifest = `{"name": "Partial", "images": [{"name": "white_egg", "path": "egg.png"}]}`
+
+// classicFiles — манифест классической темы без самих файлов, кроме
+// картинки сердца: загрузка не трогает ни видеокарту, ни звук.
+func classicFiles(t *testing.T) fstest.MapFS {
+	t.Helper()
+	data, err := assetFiles.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	classic, err := readManifest(assetFiles, manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	heart, _ := classic.entry(classic.Images, "heart_full")
+	return fstest.MapFS{
+		manifestPath: {Data: data},
+		heart.Path:   {Data: []byte("not a png")},
+	}
+}
+
+func zipTheme(t *testing.T) fs.FS {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(partialManifest)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr
+}
+
+func dirTheme(t *testing.T) fs.FS {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, manifestPath), []byte(partialManifest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return os.DirFS(dir)
+}
+
+// Тема из каталога настроек перечисляет не всё: остальное берётся из
+// классической, а недостающие файлы остаются заглушками.
+func TestPartialThemeInheritsClassic(t *testing.T) {
+	// loadResources раздаёт ресурсы глобальным переменным
+	t.Cleanup(func() {
+		if assets != nil {
+			assets.apply()
+		}
+	})
+	for name, theme := range map[string]fs.FS{"zip": zipTheme(t), "directory": dirTheme(t)} {
+		t.Run(name, func(t *testing.T) {
+			base := classicFiles(t)
+			r, err := loadResources(theme, base, manifestPath, false)
+			if err != nil {
+				t.Fatalf("partial theme rejected: %v", err)
+			}
+			if r.manifest.Name != "Partial" {
+				t.Fatalf("theme name %q, want Partial", r.manifest.Name)
+			}
+			egg, _ := r.manifest.entry(r.manifest.Images, "white_egg")
+			if egg.inherited || egg.Path != "egg.png" {
+				t.Fatalf("own image replaced by %s", egg.Path)
+			}
+			if got := r.resolve(egg); got != "" {
+				t.Fatalf("missing egg.png resolved to %q, want a placeholder", got)
+			}
+			heart, ok := r.manifest.entry(r.manifest.Images, "heart_full")
+			if !ok || !heart.inherited {
+				t.Fatal("heart_full was not inherited from classic")
+			}
+			if got := r.resolve(heart); got != heart.Path {
+				t.Fatalf("inherited heart resolved to %q, want classic %s", got, heart.Path)
+			}
+			for _, name := range soundNames {
+				if s, ok := r.manifest.entry(r.manifest.Sounds, name); !ok || !s.inherited {
+					t.Fatalf("sound %q was not inherited from classic", name)
+				}
+			}
+			if !r.manifest.spritesInherited {
+				t.Fatal("sprite sheets were not inherited from classic")
+			}
+			if len(r.images) != 0 || len(r.sounds) != 0 {
+				t.Fatalf("loaded %d images and %d sounds from missing files", len(r.images), len(r.sounds))
+			}
+		})
+	}
+}
+
+// Классическая тема ничего не наследует и должна перечислять всё.
+func TestClassicThemeMustListEverything(t *testing.T) {
+	if _, err := loadResources(dirTheme(t), nil, manifestPath, false); err == nil {
+		t.Fatal("expected an error for a partial classic manifest")
+	}
+}
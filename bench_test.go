@@ -0,0 +1,192 @@
+//go:build bench
+
+package main
+
+import (
+	"log"
+	"os"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+
+	"egg_catcher2/api"
+	"egg_catcher2/sim"
+)
+
+// Выделения памяти за кадр: go test -tags bench -run=Allocs -bench=.
+// -benchmem . играет партию автопилотом без входа на сервер. В
+// установившемся режиме кадр не должен выделять память, это проверяет
+// TestGameWrapperDrawAllocs. Ebiten рисует только внутри игрового цикла,
+// поэтому TestMain запускает тесты из Update, как это делают тесты самого
+// Ebiten; нужен дисплей, в CI — xvfb-run. Без тега bench тесты пакета
+// обходятся без дисплея.
+
+const (
+	benchWarmup = 120 // Кадров без замера, пока заполняются буферы
+	benchFlush  = 60  // Через сколько кадров выполнять накопленную отрисовку
+)
+
+// testLoop запускает тесты в первом Update и завершает игровой цикл.
+type testLoop struct {
+	m    *testing.M
+	code int
+}
+
+func (l *testLoop) Update() error {
+	l.code = l.m.Run()
+	return ebiten.Termination
+}
+
+func (l *testLoop) Draw(screen *ebiten.Image) {}
+
+func (l *testLoop) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return screenWidth, screenHeight
+}
+
+func TestMain(m *testing.M) {
+	audioContext = audio.NewContext(44100)
+	t, _ := findTheme(defaultTheme)
+	var err error
+	if assets, err = t.load(); err != nil {
+		log.Fatal(err)
+	}
+	loop := &testLoop{m: m}
+	if err := ebiten.RunGame(loop); err != nil {
+		log.Fatal(err)
+	}
+	os.Exit(loop.code)
+}
+
+// autopilotInput ведёт волка автопилотом симуляции.
+type autopilotInput struct {
+	game   *Game
+	inputs [1]sim.Input
+}
+
+func (s *autopilotInput) Next() ([]sim.Input, bool) {
+	s.inputs[0] = s.game.Autopilot(0)
+	return s.inputs[:], true
+}
+
+// resetBenchGame начинает в g партию на автопилоте и разогревает её.
+func resetBenchGame(tb testing.TB, g *Game) {
+	tb.Helper()
+	*g = *NewGame(0, nil, nil, nil, nil, nil)
+	g.input = &autopilotInput{game: g}
+	g.autoPause = false // Окно тестов может быть и не в фокусе
+	for range benchWarmup {
+		benchStep(tb, g)
+	}
+}
+
+// benchStep делает кадр партии. Закончившаяся партия начинается заново:
+// экран Game Over отправил бы результат.
+func benchStep(tb testing.TB, g *Game) {
+	tb.Helper()
+	if g.Over() {
+		resetBenchGame(tb, g)
+	}
+	if err := g.Update(); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+var benchDaily = api.DailyChallenge{Day: "2024-01-01"}
+
+// benchScenes — то, что GameWrapper.Draw рисует поверх партии. Сцена
+// задаётся перед каждым кадром: новая партия её сбрасывает.
+var benchScenes = []struct {
+	name  string
+	apply func(g *Game)
+}{
+	{"play", func(g *Game) {}},
+	{"shaken", func(g *Game) { g.particles.shake = shakeFrames }},
+	{"daily", func(g *Game) { g.daily = &benchDaily }},
+	{"paused", func(g *Game) { g.isPaused = true }},
+}
+
+// benchmarkDraw замеряет кадр draw в сцене scene.
+func benchmarkDraw(b *testing.B, scene func(*Game), draw func(g *Game, screen *ebiten.Image)) {
+	g := new(Game)
+	resetBenchGame(b, g)
+	screen := ebiten.NewImage(screenWidth, screenHeight)
+	defer screen.Deallocate()
+	pixels := make([]byte, 4*screenWidth*screenHeight)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		scene(g)
+		draw(g, screen)
+		if i%benchFlush == benchFlush-1 {
+			// Команды отрисовки копятся до конца кадра, а весь замер идёт
+			// в одном Update: чтение пикселей выполняет их, чтобы очередь
+			// не росла. Заодно партия продвигается, чтобы кадры менялись.
+			b.StopTimer()
+			screen.ReadPixels(pixels)
+			for range benchFlush {
+				benchStep(b, g)
+			}
+			b.StartTimer()
+		}
+	}
+}
+
+func BenchmarkGameUpdate(b *testing.B) {
+	g := new(Game)
+	resetBenchGame(b, g)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if g.Over() {
+			b.StopTimer()
+			resetBenchGame(b, g)
+			b.StartTimer()
+		}
+		benchStep(b, g)
+	}
+}
+
+func BenchmarkGameDraw(b *testing.B) {
+	benchmarkDraw(b, benchScenes[0].apply, func(g *Game, screen *ebiten.Image) {
+		g.Draw(screen)
+	})
+}
+
+func BenchmarkGameWrapperDraw(b *testing.B) {
+	for _, scene := range benchScenes {
+		b.Run(scene.name, func(b *testing.B) {
+			w := &GameWrapper{}
+			benchmarkDraw(b, scene.apply, func(g *Game, screen *ebiten.Image) {
+				w.game = g
+				w.Draw(screen)
+			})
+		})
+	}
+}
+
+// Кадр в установившемся режиме не выделяет память ни в одной сцене.
+func TestGameWrapperDrawAllocs(t *testing.T) {
+	for _, scene := range benchScenes {
+		t.Run(scene.name, func(t *testing.T) {
+			g := new(Game)
+			resetBenchGame(t, g)
+			w := &GameWrapper{game: g}
+			screen := ebiten.NewImage(screenWidth, screenHeight)
+			defer screen.Deallocate()
+			// Буферы и очередь команд сначала дорастают до своего размера
+			for range benchFlush {
+				scene.apply(g)
+				w.Draw(screen)
+			}
+			screen.ReadPixels(make([]byte, 4*screenWidth*screenHeight))
+			allocs := testing.AllocsPerRun(benchFlush-1, func() {
+				scene.apply(g)
+				w.Draw(screen)
+			})
+			if allocs > 0 {
+				t.Fatalf("%.1f allocations per frame, want 0", allocs)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"egg_catcher2/api"
+	"egg_catcher2/sim"
+)
+
+// Точки этапов на карте кампании, в координатах экрана
+const (
+	stageMapY      = 260
+	stageNodeSize  = 36
+	stageMapMargin = 120
+)
+
+// CampaignState — карта кампании: пройденные и открытые этапы можно
+// выбрать, следующие за ними закрыты.
+type CampaignState struct {
+	completed  int // Последний пройденный этап
+	selected   int // Этап под курсором, 0 — ни один
+	errorMsg   string
+	backButton Button
+	stage      int // Выбранный этап
+	done       bool
+}
+
+func NewCampaignState(backend api.Backend) *CampaignState {
+	s := &CampaignState{
+		backButton: Button{
+			x:     screenWidth/3 - buttonWidth/2,
+			y:     screenHeight/3 + 150,
+			w:     buttonWidth,
+			h:     gameOverButtonHeight,
+			label: "Back",
+		},
+	}
+	if backend == nil {
+		s.errorMsg = "server not configured"
+		return s
+	}
+	campaign, err := backend.Campaign(currentSessionToken)
+	if err != nil {
+		log.Printf("Error loading campaign progress: %v", err)
+		s.errorMsg = err.Error()
+	}
+	s.completed = campaign.Completed
+	return s
+}
+
+// stagePos возвращает центр точки этапа n (с единицы) на карте.
+func stagePos(n int) (float32, float32) {
+	x := float32(screenWidth / 2)
+	if len(sim.Stages) > 1 {
+		x = stageMapMargin + float32(n-1)*(screenWidth-2*stageMapMargin)/float32(len(sim.Stages)-1)
+	}
+	// Точки идут змейкой, чтобы карта не была просто строкой
+	y := float32(stageMapY)
+	if n%2 == 0 {
+		y -= 60
+	}
+	return x, y
+}
+
+// unlocked сообщает, можно ли начать этап n: открыт следующий за
+// последним пройденным и все пройденные.
+func (s *CampaignState) unlocked(n int) bool {
+	return n <= s.completed+1
+}
+
+func (s *CampaignState) Update() error {
+	cx, cy := ebiten.CursorPosition()
+	mx, my := float64(cx), float64(cy)
+	s.backButton.hovered = s.backButton.IsInside(mx, my)
+	s.selected = 0
+	for n := 1; n <= len(sim.Stages); n++ {
+		x, y := stagePos(n)
+		if float32(mx) >= x-stageNodeSize/2 && float32(mx) <= x+stageNodeSize/2 &&
+			float32(my) >= y-stageNodeSize/2 && float32(my) <= y+stageNodeSize/2 {
+			s.selected = n
+		}
+	}
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		if s.backButton.hovered {
+			s.done = true
+		} else if s.selected != 0 && s.unlocked(s.selected) {
+			s.stage = s.selected
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		s.done = true
+	}
+	return nil
+}
+
+func (s *CampaignState) Draw(screen *ebiten.Image) {
+	if imgBackgroundMenu != nil {
+		screen.DrawImage(imgBackgroundMenu, nil)
+	} else {
+		screen.Fill(color.RGBA{0, 128, 255, 255})
+	}
+
+	path := color.RGBA{255, 255, 255, 160}
+	for n := 2; n <= len(sim.Stages); n++ {
+		px, py := stagePos(n - 1)
+		x, y := stagePos(n)
+		vector.StrokeLine(screen, px, py, x, y, 3, path, true)
+	}
+	for n := 1; n <= len(sim.Stages); n++ {
+		x, y := stagePos(n)
+		c := color.RGBA{90, 90, 90, 255}
+		switch {
+		case n <= s.completed:
+			c = color.RGBA{0, 180, 0, 255}
+		case s.unlocked(n):
+			c = color.RGBA{255, 200, 0, 255}
+		}
+		if n == s.selected && s.unlocked(n) {
+			vector.DrawFilledCircle(screen, x, y, stageNodeSize/2+4, color.RGBA{255, 255, 255, 255}, true)
+		}
+		vector.DrawFilledCircle(screen, x, y, stageNodeSize/2, c, true)
+		ebitenutil.DebugPrintAt(screen, fmt.Sprint(n), int(x)-3, int(y)-8)
+		label := sim.Stages[n-1].Name
+		if !s.unlocked(n) {
+			label += " (locked)"
+		}
+		ebitenutil.DebugPrintAt(screen, label, int(x)-len(label)*3, int(y)+stageNodeSize/2+6)
+	}
+
+	textImg := textLayer()
+	ebitenutil.DebugPrintAt(textImg, "Campaign", 20, 15)
+	ebitenutil.DebugPrintAt(textImg, fmt.Sprintf("Stages cleared: %d/%d", min(s.completed, len(sim.Stages)), len(sim.Stages)), 20, 35)
+	if s.selected != 0 {
+		st := sim.Stages[s.selected-1]
+		boss, _ := sim.BossTypeByID(st.Boss)
+		ebitenutil.DebugPrintAt(textImg, fmt.Sprintf("Stage %d: %s - boss %s at %d points", s.selected, st.Name, boss.Name, st.BossScore), 20, 55)
+	}
+	if s.errorMsg != "" {
+		ebitenutil.DebugPrintAt(textImg, "Error: "+s.errorMsg, 20, 75)
+	}
+	s.drawButton(textImg, &s.backButton)
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(1.5, 1.5)
+	screen.DrawImage(textImg, op)
+}
+
+func (s *CampaignState) drawButton(screen *ebiten.Image, b *Button) {
+	buttonColor := color.RGBA{0, 128, 255, 255}
+	if b.hovered {
+		buttonColor = color.RGBA{0, 192, 255, 255}
+	}
+	ebitenutil.DrawRect(screen, b.x, b.y, b.w, b.h, buttonColor)
+	ebitenutil.DebugPrintAt(screen, b.label, int(b.x+(b.w-float64(len(b.label)*7))/2), int(b.y+b.h/2))
+}
+
+func (s *CampaignState) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return screenWidth, screenHeight
+}
+
+// startStage начинает этап кампании n на seed билета новой партии.
+// Призрак в кампании не участвует: лучший забег записан в обычной партии.
+func (g *Game) startStage(n int) {
+	*g = *NewGame(g.playerID, g.loseHeartPlayer, g.gainHeartPlayer, g.scoreHeartPlayer, g.bossMusic, g.bossHitEffect)
+	g.World = sim.NewStageWorld(g.Seed, 1, n)
+	g.recording = sim.NewRecording(g.Seed)
+	g.recording.Stage = n
+	g.setDifficulty(difficulty)
+	log.Printf("Started campaign stage %d", n)
+}
+
+func (g *Game) drawCampaignHUD(screen *ebiten.Image) {
+	g.text = fmt.Appendf(g.text[:0], "Stage %d: %s", g.Stage, g.CurrentStage().Name)
+	drawTextBytes(screen, g.text, 10, 40, 1)
+}
+
+// stageBackground возвращает фон этапа кампании; если в теме его нет —
+// обычный фон.
+func (g *Game) stageBackground() *ebiten.Image {
+	if s := g.CurrentStage(); s != nil {
+		if img := assets.images[s.Background]; img != nil {
+			return img
+		}
+	}
+	return imgBackgroundMain
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+
+	"egg_catcher2/relay"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "TCP listen address for online games")
+	flag.Parse()
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("Error listening on %s: %v", *addr, err)
+	}
+	log.Printf("Egg Catcher relay listening on %s", *addr)
+	if err := relay.NewServer().Serve(l); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"egg_catcher2/server"
+	"egg_catcher2/store"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "HTTP listen address")
+	dbURL := flag.String("db", os.Getenv("DATABASE_URL"), "PostgreSQL connection URL (default $DATABASE_URL)")
+	memory := flag.Bool("memory", false, "Keep all data in memory instead of PostgreSQL")
+	clear := flag.Bool("clear", false, "Clear all database data")
+	dailySecret := flag.String("daily-secret", os.Getenv("DAILY_SECRET"), "Secret for daily challenge seeds (default $DAILY_SECRET)")
+	flag.Parse()
+
+	var st store.Store
+	if *memory {
+		log.Printf("Using in-memory store, data will be lost on exit")
+		st = store.NewMemory()
+	} else {
+		if *dbURL == "" {
+			log.Fatal("Database URL is not set, use -db or DATABASE_URL")
+		}
+		sqlStore, err := store.Open(*dbURL)
+		if err != nil {
+			log.Fatalf("Error initializing database: %v", err)
+		}
+		defer sqlStore.Close()
+		if *clear {
+			if err := sqlStore.Clear(); err != nil {
+				log.Fatalf("Error clearing database: %v", err)
+			}
+			fmt.Println("Database cleared successfully")
+			return
+		}
+		st = sqlStore
+	}
+
+	service := server.NewService(st)
+	if *dailySecret != "" {
+		service.SetDailySecret(*dailySecret)
+	} else {
+		log.Printf("Daily challenge secret is not set, seeds are predictable")
+	}
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           server.NewHandler(service),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	// По сигналу сервер дожидается текущих запросов, а main возвращается
+	// и закрывает базу в defer: log.Fatal его бы пропустил
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errs := make(chan error, 1)
+	go func() {
+		log.Printf("Egg Catcher server listening on %s", *addr)
+		errs <- srv.ListenAndServe()
+	}()
+	select {
+	case err := <-errs:
+		// Сервер не смог начать приём соединений: запросов ещё не было
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+	log.Printf("Shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Error shutting down server: %v", err)
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// configDir возвращает каталог настроек игры, создавая его при необходимости.
+func configDir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find config directory: %v", err)
+	}
+	dir := filepath.Join(base, "egg_catcher2")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %v", err)
+	}
+	return dir, nil
+}
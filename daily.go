@@ -0,0 +1,55 @@
+package main
+
+import (
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"egg_catcher2/api"
+	"egg_catcher2/sim"
+)
+
+// startDaily начинает ежедневное испытание: сервер засчитывает попытку
+// и выдаёт общий для всех игроков seed.
+func (g *Game) startDaily() {
+	if backend == nil {
+		return
+	}
+	daily, err := backend.StartDaily(currentSessionToken)
+	if err != nil {
+		log.Printf("Error starting daily challenge: %v", err)
+		g.statusMsg = err.Error()
+		return
+	}
+	ghostEnabled := g.ghostEnabled
+	*g = *newGame(daily.Seed, g.playerID, g.loseHeartPlayer, g.gainHeartPlayer, g.scoreHeartPlayer, g.bossMusic, g.bossHitEffect)
+	if ghostEnabled {
+		g.toggleGhost()
+	}
+	// Испытание для всех одно, сервер принимает его только на обычной
+	// сложности
+	g.setDifficulty(sim.DifficultyNormal)
+	g.daily = &daily
+	log.Printf("Started daily challenge %s", daily.Day)
+}
+
+func loadDailyLeaderboard() []api.Player {
+	if backend == nil {
+		return []api.Player{}
+	}
+	scores, err := backend.DailyLeaderboard("", 5)
+	if err != nil {
+		log.Printf("Error loading daily leaderboard: %v", err)
+		return []api.Player{}
+	}
+	leaderboard := make([]api.Player, 0, len(scores))
+	for _, s := range scores {
+		leaderboard = append(leaderboard, api.Player{Name: s.Name, HighScore: s.Score})
+	}
+	return leaderboard
+}
+
+func (g *Game) drawDailyHUD(screen *ebiten.Image) {
+	g.text = append(append(g.text[:0], "Daily Challenge "...), g.daily.Day...)
+	drawTextBytes(screen, g.text, 10, 40, 1)
+}
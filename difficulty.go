@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"egg_catcher2/sim"
+)
+
+// difficulty — сложность новых партий из настроек. Ежедневное испытание,
+// гонка с призраком и сетевая игра всегда идут на обычной сложности:
+// в них игроки должны быть в равных условиях.
+var difficulty = sim.DifficultyNormal
+
+// difficultyOrder — порядок сложностей при переключении в профиле.
+var difficultyOrder = []sim.Difficulty{sim.DifficultyEasy, sim.DifficultyNormal, sim.DifficultyHard}
+
+// nextDifficulty возвращает сложность, следующую за d, по кругу.
+func nextDifficulty(d sim.Difficulty) sim.Difficulty {
+	for i, o := range difficultyOrder {
+		if o == d && i+1 < len(difficultyOrder) {
+			return difficultyOrder[i+1]
+		}
+	}
+	return difficultyOrder[0]
+}
+
+// setDifficulty задаёт сложность ещё не начатой партии.
+func (g *Game) setDifficulty(d sim.Difficulty) {
+	g.World.Difficulty = d
+	g.recording.Difficulty = d
+}
+
+func difficultyFile() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "difficulty"), nil
+}
+
+func saveDifficultySetting(d sim.Difficulty) error {
+	path, err := difficultyFile()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(d.String()), 0o600); err != nil {
+		return fmt.Errorf("failed to save difficulty: %v", err)
+	}
+	return nil
+}
+
+func loadDifficultySetting() sim.Difficulty {
+	path, err := difficultyFile()
+	if err != nil {
+		return sim.DifficultyNormal
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return sim.DifficultyNormal
+	}
+	name := strings.TrimSpace(string(data))
+	for _, d := range difficultyOrder {
+		if d.String() == name {
+			return d
+		}
+	}
+	return sim.DifficultyNormal
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"egg_catcher2/sim"
+)
+
+// effectColors — цвета яиц-бонусов и полосок на панели эффектов.
+var effectColors = [sim.EffectCount]color.RGBA{
+	sim.EffectWideBasket:   {255, 140, 0, 255},
+	sim.EffectSlowMotion:   {80, 200, 255, 255},
+	sim.EffectMagnet:       {220, 60, 220, 255},
+	sim.EffectShield:       {60, 120, 255, 255},
+	sim.EffectDoublePoints: {60, 220, 60, 255},
+}
+
+// effectMarks подписывают яйца-бонусы, чтобы их было видно издалека.
+var effectMarks = [sim.EffectCount]string{
+	sim.EffectWideBasket:   "W",
+	sim.EffectSlowMotion:   "S",
+	sim.EffectMagnet:       "M",
+	sim.EffectShield:       "+",
+	sim.EffectDoublePoints: "x2",
+}
+
+func drawPowerUpMark(screen *ebiten.Image, egg sim.Egg) {
+	if egg.Effect == sim.EffectNone {
+		return
+	}
+	drawText(screen, effectMarks[egg.Effect], float64(int(egg.X)+eggSize/2+2), float64(int(egg.Y)-eggSize), 1)
+}
+
+// Кольцо магнита и рамка щита рисуются вектором один раз: векторный
+// путь в кадре игры выделял бы память.
+var (
+	magnetRing = func() *ebiten.Image {
+		const size = 2*sim.MagnetRadius + 4
+		img := ebiten.NewImage(size, size)
+		clr := effectColors[sim.EffectMagnet]
+		clr.A = 90
+		vector.StrokeCircle(img, size/2, size/2, sim.MagnetRadius, 2, clr, true)
+		return img
+	}()
+	shieldFrame = func() *ebiten.Image {
+		img := ebiten.NewImage(basketWidth+12, basketHeight+12)
+		vector.StrokeRect(img, 2, 2, basketWidth+8, basketHeight+8, 3, effectColors[sim.EffectShield], false)
+		return img
+	}()
+)
+
+// drawWolfEffects показывает эффекты прямо на волке: широкую корзину,
+// радиус магнита и щит.
+func drawWolfEffects(screen *ebiten.Image, wolf *sim.Wolf) {
+	centerX := wolf.X + wolfWidth/2
+	if wolf.Active(sim.EffectWideBasket) {
+		width := basketWidth * sim.WideBasketScale
+		clr := effectColors[sim.EffectWideBasket]
+		clr.A = 120
+		fillRect(screen, centerX-width/2, wolf.BasketY, width, 6, clr)
+	}
+	if wolf.Active(sim.EffectMagnet) {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(centerX-float64(magnetRing.Bounds().Dx())/2, wolf.BasketY-float64(magnetRing.Bounds().Dy())/2)
+		screen.DrawImage(magnetRing, op)
+	}
+	if wolf.Active(sim.EffectShield) {
+		basketX := wolf.X - basketWidth/2 + wolfWidth/2
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(basketX-6, wolf.BasketY-26)
+		screen.DrawImage(shieldFrame, op)
+	}
+}
+
+// drawEffectsHUD выводит действующие эффекты с полоской оставшегося
+// времени под основной статистикой.
+func (g *Game) drawEffectsHUD(screen *ebiten.Image) {
+	y := 80
+	for i := range g.Wolves {
+		wolf := &g.Wolves[i]
+		for e := sim.EffectNone + 1; e < sim.EffectCount; e++ {
+			if !wolf.Active(e) {
+				continue
+			}
+			info := sim.Effects[e]
+			frac := float64(wolf.Effects[e]) / float64(info.Duration)
+			clr := effectColors[e]
+			clr.A = 160
+			fillRect(screen, 10, float64(y), 160*frac, 16, clr)
+			b := g.text[:0]
+			if len(g.Wolves) > 1 {
+				b = append(appendInt(append(b, 'P'), i+1), ' ')
+			}
+			b = append(append(b, info.Name...), ' ')
+			b = append(appendInt(b, (wolf.Effects[e]+sim.TicksPerSecond-1)/sim.TicksPerSecond), 's')
+			g.text = b
+			drawTextBytes(screen, b, 14, float64(y), 1)
+			y += 18
+		}
+	}
+}
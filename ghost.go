@@ -0,0 +1,114 @@
+package main
+
+import (
+	"image/color"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"egg_catcher2/api"
+	"egg_catcher2/sim"
+)
+
+// ghostRun — лучший забег игрока, который проигрывается параллельно с
+// текущей партией на том же seed.
+type ghostRun struct {
+	*sim.World
+	input *recordingInputSource
+}
+
+func newGhostRun(rec *sim.Recording) *ghostRun {
+	return &ghostRun{
+		World: sim.NewWorld(rec.Seed),
+		input: &recordingInputSource{rec: rec},
+	}
+}
+
+func (gr *ghostRun) step() {
+	if gr.GameOver || gr.GameWon {
+		return
+	}
+	inputs, ok := gr.input.Next()
+	if !ok {
+		return
+	}
+	gr.Step(inputs...)
+}
+
+// startGhostRace начинает в партии без билета забег с призраком лучшей
+// партии на её seed по билету, который сервер выдаёт на этот seed. Без
+// лучшего забега партия идёт по обычному билету.
+func (g *Game) startGhostRace() {
+	ticket, rec, ok := ghostTicket()
+	if !ok {
+		ticket = newTicket(currentSessionToken)
+	}
+	g.World = sim.NewWorld(ticket.Seed)
+	g.recording = sim.NewRecording(ticket.Seed)
+	g.tickets = []int64{ticket.Nonce}
+	if !ok {
+		g.setDifficulty(difficulty)
+		return
+	}
+	// Лучший забег записан на обычной сложности, как и гонка с ним
+	g.ghost = newGhostRun(rec)
+}
+
+// ghostTicket загружает лучший забег и билет на его seed.
+func ghostTicket() (api.GameTicket, *sim.Recording, bool) {
+	if backend == nil {
+		return api.GameTicket{}, nil, false
+	}
+	run, err := backend.BestRun(currentSessionToken)
+	if err != nil {
+		log.Printf("Error loading best run: %v", err)
+		return api.GameTicket{}, nil, false
+	}
+	rec, err := sim.DecodeRecording(run.Replay)
+	if err != nil {
+		log.Printf("Error decoding best run: %v", err)
+		return api.GameTicket{}, nil, false
+	}
+	ticket, err := backend.StartGame(currentSessionToken, true)
+	if err != nil {
+		log.Printf("Error starting ghost race: %v", err)
+		return api.GameTicket{}, nil, false
+	}
+	log.Printf("Racing ghost of best run with score %d", run.Score)
+	return ticket, rec, true
+}
+
+func (g *Game) toggleGhost() {
+	g.ghostEnabled = !g.ghostEnabled
+	if g.ghostEnabled {
+		g.ghostButton.label = "Race Ghost: on"
+	} else {
+		g.ghostButton.label = "Race Ghost: off"
+	}
+}
+
+func (g *Game) drawGhost(screen *ebiten.Image) {
+	if g.ghost == nil {
+		return
+	}
+	wolf := g.ghost.Wolves[0]
+	basketX := float64(wolf.X - basketWidth/2 + wolfWidth/2)
+	if wolfImg := sheetWolf.firstFrame(); wolfImg != nil {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(2.0, 2.0)
+		op.GeoM.Translate(basketX, wolf.BasketY-20)
+		op.ColorScale.Scale(0.6, 0.8, 1, 1) // Полупрозрачный голубоватый призрак
+		op.ColorScale.ScaleAlpha(0.4)
+		screen.DrawImage(wolfImg, op)
+	} else {
+		fillRect(screen, basketX, wolf.BasketY-20, basketWidth, basketHeight, color.RGBA{120, 160, 255, 100})
+	}
+	delta := g.Wolves[0].Score - wolf.Score
+	b := appendInt(append(g.text[:0], "Ghost: "...), wolf.Score)
+	b = append(b, " ("...)
+	if delta >= 0 {
+		b = append(b, '+')
+	}
+	g.text = append(appendInt(b, delta), ')')
+	drawTextBytes(screen, g.text, 10, 40, 1)
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"egg_catcher2/relay"
+	"egg_catcher2/sim"
+)
+
+// interpolationDelay — на сколько кадров картинка отстаёт от последнего
+// снимка сервера. Запас сглаживает неровный приход снимков по сети.
+const interpolationDelay = 3
+
+// onlineGame — сетевая партия: мир считает сервер, клиент отправляет
+// ввод и рисует мир, интерполируя между снимками.
+type onlineGame struct {
+	client      *relay.Client
+	slot        int
+	names       []string
+	snapshots   []*relay.Snapshot // По возрастанию кадра
+	renderFrame float64
+	playedFrame int // Последний кадр, звуки которого уже проиграны
+}
+
+// OnlineState — экран входа в сетевую партию: ввод кода комнаты и
+// ожидание соперника.
+type OnlineState struct {
+	addr       string
+	name       string
+	room       string
+	client     *relay.Client
+	start      relay.ServerMessage
+	status     string
+	joinButton Button
+	backButton Button
+	done       bool
+	cancelled  bool
+}
+
+func NewOnlineState(addr, name string) *OnlineState {
+	if name == "" {
+		name = "Player" // Профиль не загрузился, сервер требует непустое имя
+	}
+	return &OnlineState{
+		addr: addr,
+		name: name,
+		joinButton: Button{
+			x:     screenWidth/3 - buttonWidth - 10,
+			y:     screenHeight/3 + 20,
+			w:     buttonWidth,
+			h:     buttonHeight,
+			label: "Join",
+		},
+		backButton: Button{
+			x:     screenWidth/3 + 10,
+			y:     screenHeight/3 + 20,
+			w:     buttonWidth,
+			h:     buttonHeight,
+			label: "Back",
+		},
+	}
+}
+
+func (s *OnlineState) join() {
+	client, err := relay.Dial(s.addr, s.room, s.name, newTicket(currentSessionToken))
+	if err != nil {
+		log.Printf("Error joining online game: %v", err)
+		s.status = err.Error()
+		return
+	}
+	s.client = client
+	s.status = "Connecting..."
+}
+
+func (s *OnlineState) cancel() {
+	if s.client != nil {
+		s.client.Close()
+	}
+	s.cancelled = true
+}
+
+func (s *OnlineState) Update() error {
+	if s.client == nil {
+		s.room = readInput(s.room, 20)
+	}
+	cx, cy := ebiten.CursorPosition()
+	mx, my := float64(cx), float64(cy)
+	s.joinButton.hovered = s.joinButton.IsInside(mx, my)
+	s.backButton.hovered = s.backButton.IsInside(mx, my)
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		if s.joinButton.hovered && s.client == nil {
+			s.join()
+		} else if s.backButton.hovered {
+			s.cancel()
+			return nil
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) && s.client == nil {
+		s.join()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		s.cancel()
+		return nil
+	}
+
+	for s.client != nil && !s.done {
+		select {
+		case msg, ok := <-s.client.Messages:
+			if !ok {
+				s.client = nil
+				s.status = "Connection to relay lost"
+				return nil
+			}
+			switch msg.Type {
+			case relay.MsgWaiting:
+				s.status = "Waiting for the second player..."
+			case relay.MsgStart:
+				s.start = msg
+				s.done = true
+			case relay.MsgError:
+				s.client.Close()
+				s.client = nil
+				s.status = msg.Error
+			}
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *OnlineState) Draw(screen *ebiten.Image) {
+	if imgBackgroundMenu != nil {
+		screen.DrawImage(imgBackgroundMenu, nil)
+	} else {
+		screen.Fill(color.RGBA{0, 128, 255, 255})
+	}
+
+	textImg := textLayer()
+	ebitenutil.DebugPrintAt(textImg, "Play Online", screenWidth/3-50, screenHeight/3-130)
+	ebitenutil.DebugPrintAt(textImg, "Room code (empty for quick match): "+s.room+"_", screenWidth/3-130, screenHeight/3-50)
+	if s.status != "" {
+		ebitenutil.DebugPrintAt(textImg, s.status, screenWidth/3-130, screenHeight/3-20)
+	}
+	if s.client == nil {
+		s.drawButton(textImg, &s.joinButton)
+	}
+	s.drawButton(textImg, &s.backButton)
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(1.5, 1.5)
+	screen.DrawImage(textImg, op)
+}
+
+func (s *OnlineState) drawButton(screen *ebiten.Image, b *Button) {
+	buttonColor := color.RGBA{0, 128, 255, 255}
+	if b.hovered {
+		buttonColor = color.RGBA{0, 192, 255, 255}
+	}
+	ebitenutil.DrawRect(screen, b.x, b.y, b.w, b.h, buttonColor)
+	ebitenutil.DebugPrintAt(screen, b.label, int(b.x+(b.w-float64(len(b.label)*7))/2), int(b.y+b.h/2))
+}
+
+func (s *OnlineState) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return screenWidth, screenHeight
+}
+
+// localSlot возвращает номер волка игрока за этим компьютером. В игре
+// на двоих за одним компьютером рекорд ведётся для первого игрока.
+func (g *Game) localSlot() int {
+	if g.online != nil {
+		return g.online.slot
+	}
+	return 0
+}
+
+// startOnline начинает сетевую партию после того, как сервер собрал
+// комнату. До первого снимка рисуется начальное состояние мира.
+func (g *Game) startOnline(client *relay.Client, start relay.ServerMessage) {
+	*g = *newGame(start.Seed, g.playerID, g.loseHeartPlayer, g.gainHeartPlayer, g.scoreHeartPlayer, g.bossMusic, g.bossHitEffect)
+	g.World = sim.NewMultiplayerWorld(start.Seed, len(start.Players))
+	g.recording = nil
+	g.tickets = start.Tickets
+	g.input = nil
+	g.versus = true
+	g.online = &onlineGame{client: client, slot: start.Slot, names: start.Players}
+	if g.achievements != nil {
+		g.trackAchievements(start.Slot)
+	}
+	log.Printf("Started online game as player %d: %v", start.Slot+1, start.Players)
+}
+
+// updateOnline отправляет ввод, принимает снимки сервера и собирает мир
+// для отрисовки.
+func (g *Game) updateOnline() error {
+	o := g.online
+	if err := o.client.SendInput(keyboardInput(0)); err != nil {
+		log.Printf("Error sending input: %v", err)
+	}
+	for {
+		var msg relay.ServerMessage
+		var ok bool
+		select {
+		case msg, ok = <-o.client.Messages:
+		default:
+			return g.advanceOnline()
+		}
+		if !ok {
+			g.endOnline("Connection to relay lost")
+			return nil
+		}
+		switch msg.Type {
+		case relay.MsgState:
+			o.snapshots = append(o.snapshots, msg.State)
+		case relay.MsgLeft:
+			g.statusMsg = fmt.Sprintf("%s left the game", o.names[msg.Slot])
+			log.Print(g.statusMsg)
+		case relay.MsgEnd:
+			g.finishOnline(msg)
+			return nil
+		case relay.MsgError:
+			g.endOnline(msg.Error)
+			return nil
+		}
+	}
+}
+
+// advanceOnline сдвигает картинку на кадр и интерполирует мир между
+// двумя снимками вокруг этого кадра.
+func (g *Game) advanceOnline() error {
+	o := g.online
+	if len(o.snapshots) == 0 {
+		return nil
+	}
+	latest := o.snapshots[len(o.snapshots)-1]
+	o.renderFrame++
+	// При отставании больше двойной задержки картинка догоняет сервер
+	if o.renderFrame < float64(latest.Frame-2*interpolationDelay) {
+		o.renderFrame = float64(latest.Frame - interpolationDelay)
+	}
+	if o.renderFrame > float64(latest.Frame) {
+		o.renderFrame = float64(latest.Frame)
+	}
+
+	for _, s := range o.snapshots {
+		if s.Frame > o.playedFrame && float64(s.Frame) <= o.renderFrame {
+			g.handleEvents(s.Events)
+			o.playedFrame = s.Frame
+		}
+	}
+
+	// Интерполяция идёт от последнего снимка не позже renderFrame к
+	// следующему за ним
+	from := 0
+	for i, s := range o.snapshots {
+		if float64(s.Frame) <= o.renderFrame {
+			from = i
+		}
+	}
+	o.snapshots = o.snapshots[from:]
+	if len(o.snapshots) < 2 {
+		g.World = o.snapshots[0].World()
+		return nil
+	}
+	a, b := o.snapshots[0], o.snapshots[1]
+	t := math.Max(0, (o.renderFrame-float64(a.Frame))/float64(b.Frame-a.Frame))
+	g.World = interpolate(a, b, t)
+	return nil
+}
+
+// interpolate строит мир по снимку b, сдвигая волков и яйца на долю t
+// пути от их положения в снимке a. Новые яйца появляются сразу на месте.
+func interpolate(a, b *relay.Snapshot, t float64) *sim.World {
+	w := b.World()
+	for i := range w.Wolves {
+		if i < len(a.Wolves) {
+			w.Wolves[i].X = a.Wolves[i].X + (b.Wolves[i].X-a.Wolves[i].X)*t
+		}
+	}
+	prev := make(map[int]sim.Egg, len(a.Eggs))
+	for _, egg := range a.Eggs {
+		prev[egg.ID] = egg
+	}
+	for i := range w.Eggs {
+		egg := &w.Eggs[i]
+		if old, ok := prev[egg.ID]; ok {
+			egg.X = old.X + (egg.X-old.X)*t
+			egg.Y = old.Y + (egg.Y-old.Y)*t
+		}
+	}
+	if w.Boss != nil && a.Boss != nil {
+		w.Boss.X = a.Boss.X + (w.Boss.X-a.Boss.X)*t
+	}
+	return w
+}
+
+// finishOnline показывает итог партии и сохраняет запись сервера, чтобы
+// отправить результат на игровой сервер как обычную партию.
+func (g *Game) finishOnline(msg relay.ServerMessage) {
+	g.online.client.Close()
+	g.closeOnlineMenu()
+	g.World = msg.State.World()
+	g.GameOver = true
+	rec, err := sim.DecodeRecording(msg.Replay)
+	if err != nil {
+		log.Printf("Error decoding online game replay: %v", err)
+		g.saved = true
+		return
+	}
+	g.recording = rec
+	g.handleEvents(msg.State.Events)
+	log.Printf("Online game finished at frame %d", msg.State.Frame)
+}
+
+// endOnline завершает партию без результата, когда связь прервалась.
+func (g *Game) endOnline(reason string) {
+	log.Printf("Online game ended: %s", reason)
+	g.online.client.Close()
+	g.closeOnlineMenu()
+	g.statusMsg = reason
+	g.GameOver = true
+	g.saved = true
+}
+
+// closeOnlineMenu убирает меню, открытое поверх закончившейся сетевой
+// партии.
+func (g *Game) closeOnlineMenu() {
+	if g.isPaused {
+		g.isPaused = false
+		applyMusicVolume(false)
+	}
+}
+
+func saveOnlineData(g *Game) error {
+	player, err := backend.SubmitGame(currentSessionToken, g.gameResult(g.online.slot, g.recording.Encode()))
+	if err != nil {
+		log.Printf("Failed to save online game for player ID %d: %v", g.playerID, err)
+		return fmt.Errorf("failed to save game data: %v", err)
+	}
+	g.record = player.HighScore
+	return nil
+}
+
+func (g *Game) drawOnlineHUD(screen *ebiten.Image) {
+	o := g.online
+	b := appendInt(append(g.text[:0], "Online: you are P"...), o.slot+1)
+	g.text = append(append(append(b, " ("...), o.names[o.slot]...), ')')
+	drawTextBytes(screen, g.text, 10, 40, 1)
+	if g.statusMsg != "" {
+		ebitenutil.DebugPrintAt(screen, g.statusMsg, 10, 56)
+	}
+}
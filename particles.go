@@ -0,0 +1,199 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"egg_catcher2/sim"
+)
+
+const (
+	maxParticles = 512
+	groundY      = screenHeight - 20 // Земля, на которой разбиваются яйца
+	flashFrames  = 12
+	shakeFrames  = 15
+	shakeOffset  = 6.0 // Наибольший сдвиг экрана при тряске
+)
+
+// particle — одна частица брызг, искр или взрыва.
+type particle struct {
+	x, y    float64
+	vx, vy  float64
+	gravity float64
+	size    float64
+	ttl     int
+	life    int // Начальное время жизни, для затухания
+	clr     color.RGBA
+}
+
+// particleSystem хранит частицы в массиве постоянного размера, чтобы
+// кадр игры не выделял память. Живые частицы лежат в начале массива.
+type particleSystem struct {
+	particles [maxParticles]particle
+	alive     int
+	rng       *rand.Rand // Случайность только для красоты, не из симуляции
+	flash     int        // Оставшиеся кадры красной вспышки
+	shake     int        // Оставшиеся кадры тряски
+	op        ebiten.DrawImageOptions
+	buffer    *ebiten.Image // Кадр игры для сдвига при тряске
+}
+
+// whitePixel растягивается и окрашивается для рисования частиц и
+// вспышки без выделения памяти.
+var whitePixel = func() *ebiten.Image {
+	img := ebiten.NewImage(1, 1)
+	img.Fill(color.White)
+	return img
+}()
+
+func newParticleSystem() *particleSystem {
+	return &particleSystem{rng: rand.New(rand.NewSource(rand.Int63()))}
+}
+
+// spawn добавляет частицу; если пул заполнен, новая частица пропадает.
+func (ps *particleSystem) spawn(p particle) {
+	if ps.alive == maxParticles {
+		return
+	}
+	p.life = p.ttl
+	ps.particles[ps.alive] = p
+	ps.alive++
+}
+
+// burst разбрасывает n частиц из точки со скоростью до speed.
+func (ps *particleSystem) burst(x, y float64, n int, speed, gravity float64, ttl int, clr color.RGBA, upward bool) {
+	for range n {
+		angle := ps.rng.Float64() * 2 * math.Pi
+		if upward {
+			angle = math.Pi + ps.rng.Float64()*math.Pi // Только вверх
+		}
+		v := speed * (0.3 + 0.7*ps.rng.Float64())
+		ps.spawn(particle{
+			x:       x,
+			y:       y,
+			vx:      math.Cos(angle) * v,
+			vy:      math.Sin(angle) * v,
+			gravity: gravity,
+			size:    2 + ps.rng.Float64()*2,
+			ttl:     ttl/2 + ps.rng.Intn(ttl/2+1),
+			clr:     clr,
+		})
+	}
+}
+
+// splat — желток и скорлупа разбитого о землю яйца.
+func (ps *particleSystem) splat(x float64, gold bool) {
+	yolk := color.RGBA{255, 200, 0, 255}
+	if gold {
+		yolk = color.RGBA{255, 160, 0, 255}
+	}
+	ps.burst(x, groundY, 14, 3, 0.2, 40, yolk, true)
+	ps.burst(x, groundY, 8, 2.5, 0.25, 30, color.RGBA{250, 250, 240, 255}, true)
+}
+
+func (ps *particleSystem) sparkle(x, y float64) {
+	ps.burst(x, y, 16, 2.5, 0, 30, color.RGBA{255, 240, 120, 255}, false)
+	ps.burst(x, y, 6, 1.5, 0, 20, color.RGBA{255, 255, 255, 255}, false)
+}
+
+func (ps *particleSystem) explosion(x, y float64) {
+	ps.burst(x, y, 40, 5, 0.05, 45, color.RGBA{255, 120, 0, 255}, false)
+	ps.burst(x, y, 20, 3, 0.05, 35, color.RGBA{255, 230, 80, 255}, false)
+	ps.burst(x, y, 12, 1.5, -0.02, 60, color.RGBA{90, 90, 90, 255}, false)
+}
+
+// update продвигает частицы на кадр симуляции и убирает погасшие,
+// переставляя на их место последнюю живую.
+func (ps *particleSystem) update() {
+	for i := 0; i < ps.alive; {
+		p := &ps.particles[i]
+		p.ttl--
+		if p.ttl <= 0 {
+			ps.alive--
+			ps.particles[i] = ps.particles[ps.alive]
+			continue
+		}
+		p.vy += p.gravity
+		p.x += p.vx
+		p.y += p.vy
+		if p.y > groundY && p.vy > 0 {
+			p.y = groundY
+			p.vx *= 0.5
+			p.vy = 0
+		}
+		i++
+	}
+	if ps.flash > 0 {
+		ps.flash--
+	}
+	if ps.shake > 0 {
+		ps.shake--
+	}
+}
+
+func (ps *particleSystem) draw(screen *ebiten.Image) {
+	for i := 0; i < ps.alive; i++ {
+		p := &ps.particles[i]
+		ps.op.GeoM.Reset()
+		ps.op.GeoM.Scale(p.size, p.size)
+		ps.op.GeoM.Translate(p.x-p.size/2, p.y-p.size/2)
+		ps.op.ColorScale.Reset()
+		scaleWithRGBA(&ps.op.ColorScale, p.clr)
+		ps.op.ColorScale.ScaleAlpha(float32(p.ttl) / float32(p.life))
+		screen.DrawImage(whitePixel, &ps.op)
+	}
+	if ps.flash > 0 {
+		ps.op.GeoM.Reset()
+		ps.op.GeoM.Scale(screenWidth, screenHeight)
+		ps.op.ColorScale.Reset()
+		scaleWithRGBA(&ps.op.ColorScale, color.RGBA{255, 0, 0, 255})
+		ps.op.ColorScale.ScaleAlpha(0.4 * float32(ps.flash) / flashFrames)
+		screen.DrawImage(whitePixel, &ps.op)
+	}
+}
+
+// drawShaken рисует кадр игры со сдвигом, пока трясётся экран. Буфер для
+// кадра создаётся один раз.
+func (ps *particleSystem) drawShaken(screen *ebiten.Image, draw func(*ebiten.Image)) {
+	if ps.shake == 0 {
+		draw(screen)
+		return
+	}
+	if ps.buffer == nil {
+		ps.buffer = ebiten.NewImage(screenWidth, screenHeight)
+	}
+	ps.buffer.Clear()
+	draw(ps.buffer)
+	strength := shakeOffset * float64(ps.shake) / shakeFrames
+	ps.op.GeoM.Reset()
+	ps.op.GeoM.Translate((ps.rng.Float64()*2-1)*strength, (ps.rng.Float64()*2-1)*strength)
+	ps.op.ColorScale.Reset()
+	screen.DrawImage(ps.buffer, &ps.op)
+}
+
+// subscribeParticles связывает эффекты с событиями партии: брызги при
+// промахе, искры при поимке золотого яйца, вспышку при потере жизни,
+// взрыв и тряску при попадании по боссу.
+func (g *Game) subscribeParticles(bus *sim.Bus) {
+	ps := g.particles
+	bus.Subscribe(sim.EventEggMissed, func(e sim.Event) {
+		ps.splat(e.X, e.Value == 2)
+	})
+	bus.Subscribe(sim.EventEggCaught, func(e sim.Event) {
+		if e.Value == 2 && e.Effect == sim.EffectNone {
+			ps.sparkle(e.X, e.Y)
+		}
+	})
+	bus.Subscribe(sim.EventLifeLost, func(sim.Event) {
+		ps.flash = flashFrames
+	})
+	bus.Subscribe(sim.EventBossHit, func(sim.Event) {
+		if g.Boss != nil && g.Boss.HitAnimationType == "explosion" {
+			ps.explosion(g.Boss.X, g.Boss.Y)
+		}
+		ps.shake = shakeFrames
+	})
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"egg_catcher2/sim"
+)
+
+// Попадание по боссу в настоящей партии трясёт экран, а взрыв при смене
+// фазы или победе рассыпает частицы.
+func TestBossHitShakesAndExplodes(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		g := NewGame(0, nil, nil, nil, nil, nil)
+		g.World = sim.NewWorld(seed)
+		bus := &sim.Bus{}
+		g.subscribeParticles(bus)
+		for !g.Over() {
+			ev := g.Step(g.Autopilot(0))
+			g.particles.update()
+			alive := g.particles.alive
+			bus.Publish(ev)
+			if !ev.Has(sim.EventBossHit) {
+				continue
+			}
+			if g.particles.shake != shakeFrames {
+				t.Fatalf("shake %d after boss hit, want %d", g.particles.shake, shakeFrames)
+			}
+			if g.Boss.HitAnimationType == "explosion" {
+				if g.particles.alive <= alive {
+					t.Fatal("explosion spawned no particles")
+				}
+				return
+			}
+		}
+	}
+	t.Fatal("no autopilot game exploded the boss")
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// duckVolume — доля громкости музыки на паузе.
+const duckVolume = 0.3
+
+// musicVolume — громкость музыки из настроек, от 0 до 1.
+var musicVolume = 1.0
+
+// pauseMenu — меню паузы поверх партии. В настройках можно сменить тему
+// и громкость музыки, не выходя из партии.
+type pauseMenu struct {
+	settings       bool // Открыты настройки
+	confirmQuit    bool // Выход из ежедневного испытания ждёт подтверждения
+	failedTheme    string
+	errorMsg       string
+	resumeButton   Button
+	restartButton  Button
+	settingsButton Button
+	menuButton     Button
+	themeButton    Button
+	volumeButton   Button
+	backButton     Button
+}
+
+func newPauseMenu() pauseMenu {
+	// Кнопки меню и настроек стоят в одном столбце по центру
+	button := func(row int, label string) Button {
+		return Button{
+			x:     screenWidth/3 - buttonWidth/2,
+			y:     screenHeight/3 - 90 + float64(row)*50,
+			w:     buttonWidth,
+			h:     gameOverButtonHeight,
+			label: label,
+		}
+	}
+	return pauseMenu{
+		resumeButton:   button(0, "Resume"),
+		restartButton:  button(1, "Restart"),
+		settingsButton: button(2, "Settings"),
+		menuButton:     button(3, "Quit to Menu"),
+		themeButton:    button(0, ""),
+		volumeButton:   button(1, ""),
+		backButton:     button(3, "Back"),
+	}
+}
+
+// music возвращает музыку, которая играет в партии сейчас.
+func (g *Game) music() *audio.Player {
+	if g.InBossRoom {
+		return g.bossMusic
+	}
+	return player
+}
+
+// applyMusicVolume задаёт громкость музыки темы; на паузе она приглушена.
+func applyMusicVolume(ducked bool) {
+	v := musicVolume
+	if ducked {
+		v *= duckVolume
+	}
+	for _, name := range []string{"music", "boss_music"} {
+		if p := assets.sounds[name]; p != nil {
+			p.SetVolume(v)
+		}
+	}
+}
+
+// pause ставит партию на паузу и сохраняет её в слот, см. saveSlot.
+func (g *Game) pause() {
+	g.isPaused = true
+	g.pauseMenu.settings = false
+	g.pauseMenu.confirmQuit = false
+	applyMusicVolume(true)
+	if err := g.saveSlot(); err != nil {
+		log.Printf("Error saving game: %v", err)
+	}
+}
+
+func (g *Game) unpause() {
+	g.isPaused = false
+	removeSlot(g.playerID)
+	applyMusicVolume(false)
+	// Партия могла быть восстановлена в комнате босса, а тема — смениться
+	// на паузе вместе с музыкой
+	if m := g.music(); m != nil && !m.IsPlaying() {
+		m.Play()
+	}
+}
+
+// resetMusic возвращает музыку меню после комнаты босса.
+func (g *Game) resetMusic() {
+	applyMusicVolume(false)
+	if g.bossMusic != nil {
+		g.bossMusic.Pause()
+	}
+	if player != nil && !player.IsPlaying() {
+		player.Play()
+	}
+}
+
+// quitToMenu бросает партию и показывает экран Game Over. Сервер
+// принимает только законченные партии, поэтому результат не отправляется.
+// Попытка ежедневного испытания при этом сгорает, так что из него меню
+// выпускает только со второго нажатия. Из сетевой партии игрок выходит,
+// закрывая соединение с сервером.
+func (g *Game) quitToMenu() {
+	removeSlot(g.playerID)
+	g.isPaused = false
+	g.resetMusic()
+	g.statusMsg = "Round abandoned, the result is not saved"
+	if g.daily != nil {
+		g.statusMsg = "Daily challenge abandoned, today's attempt is used up"
+	}
+	g.GameOver = true
+	g.saved = true
+	if g.online != nil {
+		g.online.client.Close()
+		g.statusMsg = "You left the online game"
+		log.Printf("Left online game at frame %d", g.Frame)
+		return
+	}
+	log.Printf("Round abandoned at frame %d", g.recording.Frames())
+}
+
+// canRestart сообщает, можно ли начать партию заново из меню паузы:
+// попытка ежедневного испытания одна на день, а сетевую партию ведёт
+// сервер.
+func (g *Game) canRestart() bool {
+	return g.daily == nil && g.online == nil
+}
+
+func (g *Game) updatePauseMenu() error {
+	m := &g.pauseMenu
+	cx, cy := ebiten.CursorPosition()
+	mx, my := float64(cx), float64(cy)
+	clicked := inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft)
+	if m.settings {
+		m.themeButton.hovered = m.themeButton.IsInside(mx, my)
+		m.volumeButton.hovered = m.volumeButton.IsInside(mx, my)
+		m.backButton.hovered = m.backButton.IsInside(mx, my)
+		if clicked {
+			if m.themeButton.hovered {
+				m.switchTheme()
+			} else if m.volumeButton.hovered {
+				m.changeVolume()
+			} else if m.backButton.hovered {
+				m.settings = false
+			}
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+			m.settings = false
+		}
+		return nil
+	}
+
+	m.resumeButton.hovered = m.resumeButton.IsInside(mx, my)
+	m.restartButton.hovered = g.canRestart() && m.restartButton.IsInside(mx, my)
+	m.settingsButton.hovered = m.settingsButton.IsInside(mx, my)
+	m.menuButton.hovered = m.menuButton.IsInside(mx, my)
+	if clicked {
+		if m.resumeButton.hovered {
+			g.unpause()
+		} else if m.restartButton.hovered {
+			removeSlot(g.playerID)
+			g.restart()
+		} else if m.settingsButton.hovered {
+			m.settings = true
+			m.errorMsg = ""
+		} else if m.menuButton.hovered {
+			if g.daily != nil && !m.confirmQuit {
+				m.confirmQuit = true
+			} else {
+				g.quitToMenu()
+			}
+		}
+		if !m.menuButton.hovered {
+			m.confirmQuit = false
+		}
+		return nil
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeySpace) || inpututil.IsKeyJustPressed(ebiten.KeyP) ||
+		inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.unpause()
+	}
+	return nil
+}
+
+// switchTheme включает следующую тему, как на экране профиля.
+func (m *pauseMenu) switchTheme() {
+	after := currentTheme
+	if m.failedTheme != "" {
+		after = m.failedTheme
+	}
+	name := nextTheme(after)
+	if err := switchTheme(name); err != nil {
+		log.Printf("Error switching theme: %v", err)
+		m.errorMsg = err.Error()
+		m.failedTheme = name
+		return
+	}
+	m.failedTheme = ""
+	m.errorMsg = ""
+}
+
+// changeVolume перебирает громкость музыки по кругу шагами по 25%.
+func (m *pauseMenu) changeVolume() {
+	musicVolume += 0.25
+	if musicVolume > 1 {
+		musicVolume = 0
+	}
+	applyMusicVolume(true)
+	if err := saveVolumeSetting(musicVolume); err != nil {
+		log.Printf("Error saving music volume: %v", err)
+	}
+}
+
+// drawPauseMenu рисует меню паузы поверх кадра партии.
+func (g *Game) drawPauseMenu(screen *ebiten.Image) {
+	m := &g.pauseMenu
+	fillRect(screen, 0, 0, screenWidth, screenHeight, color.RGBA{0, 0, 0, 140})
+
+	textImg := textLayer()
+	if m.settings {
+		m.themeButton.label = "Theme: " + themeTitle()
+		m.volumeButton.label = fmt.Sprintf("Music: %d%%", int(musicVolume*100+0.5))
+		ebitenutil.DebugPrintAt(textImg, "Settings", screenWidth/3-24, screenHeight/3-120)
+		g.drawButton(textImg, &m.themeButton)
+		g.drawButton(textImg, &m.volumeButton)
+		g.drawButton(textImg, &m.backButton)
+		if m.errorMsg != "" {
+			ebitenutil.DebugPrintAt(textImg, "Error: "+m.errorMsg, screenWidth/3-buttonWidth/2, screenHeight/3+120)
+		}
+	} else {
+		if g.online != nil {
+			ebitenutil.DebugPrintAt(textImg, "Menu (the game goes on)", screenWidth/3-80, screenHeight/3-120)
+		} else {
+			ebitenutil.DebugPrintAt(textImg, "Paused", screenWidth/3-18, screenHeight/3-120)
+		}
+		m.menuButton.label = "Quit to Menu"
+		if m.confirmQuit {
+			m.menuButton.label = "Lose today's attempt?"
+		}
+		g.drawButton(textImg, &m.resumeButton)
+		if g.canRestart() {
+			g.drawButton(textImg, &m.restartButton)
+		}
+		g.drawButton(textImg, &m.settingsButton)
+		g.drawButton(textImg, &m.menuButton)
+	}
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(1.5, 1.5)
+	screen.DrawImage(textImg, op)
+}
+
+func volumeFile() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "volume"), nil
+}
+
+func saveVolumeSetting(v float64) error {
+	path, err := volumeFile()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(strconv.FormatFloat(v, 'f', -1, 64)), 0o600); err != nil {
+		return fmt.Errorf("failed to save music volume: %v", err)
+	}
+	return nil
+}
+
+func loadVolumeSetting() float64 {
+	path, err := volumeFile()
+	if err != nil {
+		return 1
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 1
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	if err != nil || v < 0 || v > 1 {
+		return 1
+	}
+	return v
+}
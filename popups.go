@@ -0,0 +1,51 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"egg_catcher2/sim"
+)
+
+const popupFrames = 45 // Время жизни надписи "+N" в кадрах
+
+// scorePopup — всплывающая надпись с очками над местом поимки.
+type scorePopup struct {
+	x, y   float64
+	points int
+	ttl    int
+}
+
+// agePopups старит надписи на кадр симуляции.
+func (g *Game) agePopups() {
+	alive := g.popups[:0]
+	for _, p := range g.popups {
+		p.ttl--
+		p.y--
+		if p.ttl > 0 {
+			alive = append(alive, p)
+		}
+	}
+	g.popups = alive
+}
+
+// subscribePopups показывает очки над каждым пойманным яйцом.
+func (g *Game) subscribePopups(bus *sim.Bus) {
+	bus.Subscribe(sim.EventEggCaught, func(e sim.Event) {
+		if e.Points == 0 {
+			return
+		}
+		g.popups = append(g.popups, scorePopup{
+			x:      e.X,
+			y:      e.Y - 20,
+			points: e.Points,
+			ttl:    popupFrames,
+		})
+	})
+}
+
+func (g *Game) drawPopups(screen *ebiten.Image) {
+	for _, p := range g.popups {
+		g.text = appendInt(append(g.text[:0], '+'), p.points)
+		drawTextBytes(screen, g.text, float64(int(p.x)), float64(int(p.y)), 1)
+	}
+}
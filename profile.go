@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"egg_catcher2/achievement"
+	"egg_catcher2/api"
+)
+
+// Область графика счёта на экране профиля, в координатах экрана
+const (
+	chartX      = 345
+	chartY      = 75
+	chartWidth  = 430
+	chartHeight = 150
+)
+
+// ProfileState — экран профиля со статистикой партий и достижениями
+// игрока.
+type ProfileState struct {
+	name          string
+	stats         api.PlayerStats
+	progress      api.Achievements
+	errorMsg      string
+	accountButton Button
+	backButton    Button
+	themeButton   Button // Переключает тему по кругу
+	diffButton    Button // Переключает сложность новых партий
+	failedTheme   string // Тема, которая не загрузилась: следующий щелчок её пропускает
+	openAccount   bool   // Переход к управлению аккаунтом
+	done          bool
+}
+
+func NewProfileState(backend api.Backend, name string) *ProfileState {
+	s := &ProfileState{
+		name: name,
+		accountButton: Button{
+			x:     screenWidth/3 - buttonWidth - 10,
+			y:     screenHeight/3 + 100,
+			w:     buttonWidth,
+			h:     buttonHeight,
+			label: "Account",
+		},
+		backButton: Button{
+			x:     screenWidth/3 + 10,
+			y:     screenHeight/3 + 100,
+			w:     buttonWidth,
+			h:     buttonHeight,
+			label: "Back",
+		},
+		themeButton: Button{
+			x:     screenWidth/3 - buttonWidth - 10,
+			y:     screenHeight/3 + 160,
+			w:     buttonWidth,
+			h:     gameOverButtonHeight,
+			label: "Theme: " + themeTitle(),
+		},
+		diffButton: Button{
+			x:     screenWidth/3 + 10,
+			y:     screenHeight/3 + 160,
+			w:     buttonWidth,
+			h:     gameOverButtonHeight,
+			label: "Difficulty: " + difficulty.String(),
+		},
+	}
+	if backend == nil {
+		s.errorMsg = "server not configured"
+		return s
+	}
+	stats, err := backend.Stats(currentSessionToken)
+	if err != nil {
+		log.Printf("Error loading player stats: %v", err)
+		s.errorMsg = err.Error()
+	}
+	s.stats = stats
+	progress, err := backend.Achievements(currentSessionToken)
+	if err != nil {
+		log.Printf("Error loading achievements: %v", err)
+		s.errorMsg = err.Error()
+	}
+	s.progress = progress
+	return s
+}
+
+func (s *ProfileState) Update() error {
+	cx, cy := ebiten.CursorPosition()
+	mx, my := float64(cx), float64(cy)
+	s.accountButton.hovered = s.accountButton.IsInside(mx, my)
+	s.backButton.hovered = s.backButton.IsInside(mx, my)
+	s.themeButton.hovered = s.themeButton.IsInside(mx, my)
+	s.diffButton.hovered = s.diffButton.IsInside(mx, my)
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		if s.accountButton.hovered {
+			s.openAccount = true
+		} else if s.backButton.hovered {
+			s.done = true
+		} else if s.themeButton.hovered {
+			s.switchTheme()
+		} else if s.diffButton.hovered {
+			s.switchDifficulty()
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		s.done = true
+	}
+	return nil
+}
+
+func (s *ProfileState) Draw(screen *ebiten.Image) {
+	if imgBackgroundMenu != nil {
+		screen.DrawImage(imgBackgroundMenu, nil)
+	} else {
+		screen.Fill(color.RGBA{0, 128, 255, 255})
+	}
+
+	textImg := textLayer()
+	ebitenutil.DebugPrintAt(textImg, "Profile: "+s.name, 20, 15)
+	if s.errorMsg != "" {
+		ebitenutil.DebugPrintAt(textImg, "Error: "+s.errorMsg, 20, 150)
+	}
+
+	st := s.stats
+	bossRate := "-"
+	if st.BossFights > 0 {
+		bossRate = fmt.Sprintf("%d%% (%d/%d)", st.BossWins*100/st.BossFights, st.BossWins, st.BossFights)
+	}
+	lines := []string{
+		fmt.Sprintf("Games played: %d", st.Games),
+		fmt.Sprintf("Average score: %.1f", st.AverageScore),
+		fmt.Sprintf("Median score: %.1f", st.MedianScore),
+		fmt.Sprintf("Eggs caught: %d", st.EggsCaught),
+		fmt.Sprintf("Best level: %d", st.BestLevel),
+		"Boss win rate: " + bossRate,
+	}
+	for i, line := range lines {
+		ebitenutil.DebugPrintAt(textImg, line, 20, 40+i*16)
+	}
+	ebitenutil.DebugPrintAt(textImg, fmt.Sprintf("Last %d games", len(st.RecentScores)), chartX*2/3, 30)
+
+	unlocked := make(map[string]api.Achievement, len(s.progress.Unlocked))
+	for _, a := range s.progress.Unlocked {
+		unlocked[a.ID] = a
+	}
+	ebitenutil.DebugPrintAt(textImg, fmt.Sprintf("Achievements: %d/%d", len(unlocked), len(achievement.All)), 20, 170)
+	for i, a := range achievement.All {
+		line := "[ ] " + a.Name + " - " + a.Description
+		if got, ok := unlocked[a.ID]; ok {
+			line = "[x] " + a.Name + " - " + a.Description + got.UnlockedAt.Local().Format(" (2006-01-02)")
+		} else if a.ID == achievement.EggHoarder {
+			line += fmt.Sprintf(" (%d/%d)", min(s.progress.EggsCaught, achievement.EggsGoal), achievement.EggsGoal)
+		}
+		ebitenutil.DebugPrintAt(textImg, line, 20, 190+i*18)
+	}
+	s.drawButton(textImg, &s.accountButton)
+	s.drawButton(textImg, &s.backButton)
+	s.drawButton(textImg, &s.themeButton)
+	s.drawButton(textImg, &s.diffButton)
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(1.5, 1.5)
+	screen.DrawImage(textImg, op)
+	s.drawChart(screen)
+}
+
+// switchTheme включает следующую тему; если она не загрузилась, остаётся
+// прежняя.
+func (s *ProfileState) switchTheme() {
+	after := currentTheme
+	if s.failedTheme != "" {
+		after = s.failedTheme
+	}
+	name := nextTheme(after)
+	if err := switchTheme(name); err != nil {
+		log.Printf("Error switching theme: %v", err)
+		s.errorMsg = err.Error()
+		s.failedTheme = name
+		return
+	}
+	if s.failedTheme != "" {
+		s.failedTheme = ""
+		s.errorMsg = ""
+	}
+	s.themeButton.label = "Theme: " + themeTitle()
+}
+
+// switchDifficulty включает следующую сложность; она действует с новой
+// партии.
+func (s *ProfileState) switchDifficulty() {
+	difficulty = nextDifficulty(difficulty)
+	if err := saveDifficultySetting(difficulty); err != nil {
+		log.Printf("Error saving difficulty: %v", err)
+	}
+	s.diffButton.label = "Difficulty: " + difficulty.String()
+}
+
+// drawChart рисует счёт последних партий ломаной линией; шкала по высоте
+// подбирается по лучшему счёту среди них.
+func (s *ProfileState) drawChart(screen *ebiten.Image) {
+	scores := s.stats.RecentScores
+	axis := color.RGBA{255, 255, 255, 255}
+	vector.DrawFilledRect(screen, chartX, chartY, chartWidth, chartHeight, color.RGBA{0, 0, 0, 80}, false)
+	vector.StrokeLine(screen, chartX, chartY+chartHeight, chartX+chartWidth, chartY+chartHeight, 1, axis, false)
+	vector.StrokeLine(screen, chartX, chartY, chartX, chartY+chartHeight, 1, axis, false)
+	if len(scores) == 0 {
+		ebitenutil.DebugPrintAt(screen, "No games yet", chartX+10, chartY+10)
+		return
+	}
+	top := max(slices.Max(scores), 1)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprint(top), chartX+4, chartY)
+	point := func(i int) (float32, float32) {
+		x := float32(chartX + chartWidth/2)
+		if len(scores) > 1 {
+			x = chartX + 10 + float32(i)*(chartWidth-20)/float32(len(scores)-1)
+		}
+		y := chartY + chartHeight - float32(scores[i])*(chartHeight-10)/float32(top)
+		return x, y
+	}
+	line := color.RGBA{255, 200, 0, 255}
+	for i := range scores {
+		x, y := point(i)
+		if i > 0 {
+			px, py := point(i - 1)
+			vector.StrokeLine(screen, px, py, x, y, 2, line, true)
+		}
+		vector.DrawFilledCircle(screen, x, y, 3, line, true)
+	}
+}
+
+func (s *ProfileState) drawButton(screen *ebiten.Image, b *Button) {
+	buttonColor := color.RGBA{0, 128, 255, 255}
+	if b.hovered {
+		buttonColor = color.RGBA{0, 192, 255, 255}
+	}
+	ebitenutil.DrawRect(screen, b.x, b.y, b.w, b.h, buttonColor)
+	ebitenutil.DebugPrintAt(screen, b.label, int(b.x+(b.w-float64(len(b.label)*7))/2), int(b.y+b.h/2))
+}
+
+func (s *ProfileState) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return screenWidth, screenHeight
+}
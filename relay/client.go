@@ -0,0 +1,75 @@
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"egg_catcher2/api"
+	"egg_catcher2/sim"
+)
+
+const dialTimeout = 5 * time.Second
+
+// Client — подключение игрока к серверу сетевой игры. Сообщения сервера
+// читаются в отдельной горутине и складываются в канал Messages, который
+// закрывается при обрыве связи.
+type Client struct {
+	Messages  chan ServerMessage
+	conn      net.Conn
+	enc       *json.Encoder
+	lastInput sim.Input
+	sentInput bool
+}
+
+// Dial подключается к серверу и входит в комнату room с билетом ticket;
+// пустой room — быстрая игра с первым ожидающим соперником.
+func Dial(addr, room, name string, ticket api.GameTicket) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to relay: %v", err)
+	}
+	c := &Client{
+		Messages: make(chan ServerMessage, sendQueue),
+		conn:     conn,
+		enc:      json.NewEncoder(conn),
+	}
+	if err := c.enc.Encode(ClientMessage{Type: MsgJoin, Room: room, Name: name, Ticket: ticket}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to join room: %v", err)
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+func (c *Client) readLoop() {
+	defer close(c.Messages)
+	dec := json.NewDecoder(c.conn)
+	for {
+		var msg ServerMessage
+		if err := dec.Decode(&msg); err != nil {
+			return
+		}
+		c.Messages <- msg
+	}
+}
+
+// SendInput отправляет ввод, только если он изменился: сервер
+// применяет последний полученный ввод в каждом тике.
+func (c *Client) SendInput(in sim.Input) error {
+	if c.sentInput && in == c.lastInput {
+		return nil
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := c.enc.Encode(ClientMessage{Type: MsgInput, Input: in}); err != nil {
+		return fmt.Errorf("failed to send input: %v", err)
+	}
+	c.lastInput = in
+	c.sentInput = true
+	return nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
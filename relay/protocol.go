@@ -0,0 +1,105 @@
+// Package relay — сервер сетевой игры на двоих. Сервер сам выполняет
+// симуляцию sim.World с частотой sim.TicksPerSecond, принимает от
+// клиентов только ввод и после каждого тика рассылает им снимок мира.
+// Клиенты рисуют мир со сдвигом в несколько кадров, интерполируя между
+// снимками. Сообщения — JSON, по одному в строке поверх TCP.
+package relay
+
+import (
+	"egg_catcher2/api"
+	"egg_catcher2/sim"
+)
+
+// Типы сообщений клиента.
+const (
+	MsgJoin  = "join"
+	MsgInput = "input"
+)
+
+// Типы сообщений сервера.
+const (
+	MsgWaiting = "waiting" // Игрок в комнате, ждём соперника
+	MsgStart   = "start"   // Партия началась
+	MsgState   = "state"   // Снимок мира после тика
+	MsgLeft    = "left"    // Игрок отключился, его волк стоит на месте
+	MsgEnd     = "end"     // Партия закончилась, приложена запись
+	MsgError   = "error"
+)
+
+// Players — число игроков в комнате.
+const Players = 2
+
+type ClientMessage struct {
+	Type string `json:"type"`
+	// Room — код комнаты; с пустым кодом игрок попадает к первому
+	// ожидающему сопернику.
+	Room string `json:"room,omitempty"`
+	Name string `json:"name,omitempty"`
+	// Ticket — билет игрока на игровом сервере; seed партии складывается
+	// из билетов всех игроков комнаты.
+	Ticket api.GameTicket `json:"ticket"`
+	Input  sim.Input      `json:"input,omitempty"`
+}
+
+type ServerMessage struct {
+	Type    string    `json:"type"`
+	Slot    int       `json:"slot"` // Номер игрока, к которому относится сообщение
+	Players []string  `json:"players,omitempty"`
+	Seed    int64     `json:"seed,omitempty"`
+	Tickets []int64   `json:"tickets,omitempty"` // Nonce билетов игроков по номерам
+	State   *Snapshot `json:"state,omitempty"`
+	// Replay — закодированная sim.Recording всей партии; клиенты
+	// отправляют её на игровой сервер для проверки счёта.
+	Replay []byte `json:"replay,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Snapshot — видимое состояние мира после тика Frame.
+type Snapshot struct {
+	Frame      int        `json:"frame"`
+	Level      int        `json:"level"`
+	Wolves     []sim.Wolf `json:"wolves"`
+	Eggs       []sim.Egg  `json:"eggs"`
+	Boss       *sim.Boss  `json:"boss,omitempty"`
+	InBossRoom bool       `json:"in_boss_room,omitempty"`
+	GameOver   bool       `json:"game_over,omitempty"`
+	GameWon    bool       `json:"game_won,omitempty"`
+	Events     sim.Events `json:"events"`
+}
+
+func NewSnapshot(w *sim.World, ev sim.Events) *Snapshot {
+	s := &Snapshot{
+		Frame:      w.Frame,
+		Level:      w.Level,
+		Wolves:     append([]sim.Wolf(nil), w.Wolves...),
+		Eggs:       append([]sim.Egg(nil), w.Eggs...),
+		InBossRoom: w.InBossRoom,
+		GameOver:   w.GameOver,
+		GameWon:    w.GameWon,
+		Events:     ev,
+	}
+	if w.Boss != nil {
+		boss := *w.Boss
+		s.Boss = &boss
+	}
+	return s
+}
+
+// World собирает из снимка мир для отрисовки. Куры не двигаются и в
+// снимок не входят, они берутся из нового мира. Step у такого мира не
+// продолжает партию сервера.
+func (s *Snapshot) World() *sim.World {
+	w := sim.NewMultiplayerWorld(0, len(s.Wolves))
+	copy(w.Wolves, s.Wolves)
+	w.Eggs = append([]sim.Egg(nil), s.Eggs...)
+	w.Level = s.Level
+	w.InBossRoom = s.InBossRoom
+	w.GameOver = s.GameOver
+	w.GameWon = s.GameWon
+	w.Frame = s.Frame
+	if s.Boss != nil {
+		boss := *s.Boss
+		w.Boss = &boss
+	}
+	return w
+}
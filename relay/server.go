@@ -0,0 +1,283 @@
+package relay
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"egg_catcher2/api"
+	"egg_catcher2/sim"
+)
+
+const (
+	joinTimeout  = 10 * time.Second
+	writeTimeout = 5 * time.Second
+	sendQueue    = 128 // Снимков в очереди клиента, после чего он отключается
+	maxNameLen   = 20
+	maxRoomLen   = 20
+)
+
+// Server собирает игроков в комнаты и ведёт партию каждой комнаты в
+// своей горутине.
+type Server struct {
+	TickRate time.Duration
+	mu       sync.Mutex
+	rooms    map[string]*room // Комнаты, ждущие игроков, по коду
+	waiting  *room            // Комната быстрой игры без кода
+}
+
+func NewServer() *Server {
+	return &Server{
+		TickRate: time.Second / sim.TicksPerSecond,
+		rooms:    make(map[string]*room),
+	}
+}
+
+// Serve принимает подключения, пока слушатель не будет закрыт.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+type client struct {
+	conn   net.Conn
+	name   string
+	ticket api.GameTicket
+	slot   int
+	send   chan ServerMessage
+	done   chan struct{}
+}
+
+func (c *client) writeLoop() {
+	enc := json.NewEncoder(c.conn)
+	for {
+		select {
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := enc.Encode(msg); err != nil {
+				c.conn.Close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// post не блокирует тик: клиента, который не успевает читать, сервер
+// отключает.
+func (c *client) post(msg ServerMessage) {
+	select {
+	case c.send <- msg:
+	default:
+		log.Printf("Player '%s' is too slow, disconnecting", c.name)
+		c.conn.Close()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	dec := json.NewDecoder(conn)
+	conn.SetReadDeadline(time.Now().Add(joinTimeout))
+	var join ClientMessage
+	if err := dec.Decode(&join); err != nil {
+		log.Printf("Failed to read join from %s: %v", conn.RemoteAddr(), err)
+		return
+	}
+	c := &client{
+		conn:   conn,
+		name:   join.Name,
+		ticket: join.Ticket,
+		send:   make(chan ServerMessage, sendQueue),
+		done:   make(chan struct{}),
+	}
+	r, err := s.join(c, join)
+	if err != nil {
+		json.NewEncoder(conn).Encode(ServerMessage{Type: MsgError, Error: err.Error()})
+		return
+	}
+	go c.writeLoop()
+	defer close(c.done)
+	conn.SetReadDeadline(time.Time{})
+
+	for {
+		var msg ClientMessage
+		if err := dec.Decode(&msg); err != nil {
+			break
+		}
+		if msg.Type == MsgInput {
+			r.setInput(c, msg.Input)
+		}
+	}
+	s.leave(r, c)
+}
+
+func (s *Server) join(c *client, msg ClientMessage) (*room, error) {
+	if msg.Type != MsgJoin {
+		return nil, errors.New("expected join message")
+	}
+	if c.name == "" || len(c.name) > maxNameLen {
+		return nil, errors.New("invalid player name")
+	}
+	if len(msg.Room) > maxRoomLen {
+		return nil, errors.New("room code too long")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.waiting
+	if msg.Room != "" {
+		r = s.rooms[msg.Room]
+	}
+	if r == nil {
+		r = &room{name: msg.Room}
+		if msg.Room != "" {
+			s.rooms[msg.Room] = r
+		} else {
+			s.waiting = r
+		}
+	}
+	c.slot = len(r.clients)
+	r.clients = append(r.clients, c)
+	log.Printf("Player '%s' joined room %q as player %d", c.name, r.name, c.slot+1)
+	if len(r.clients) < Players {
+		c.post(ServerMessage{Type: MsgWaiting, Slot: c.slot})
+		return r, nil
+	}
+	// Комната заполнена: дальше ею владеет горутина партии
+	if msg.Room != "" {
+		delete(s.rooms, msg.Room)
+	} else {
+		s.waiting = nil
+	}
+	r.started = true
+	r.mu.Lock()
+	r.inputs = make([]sim.Input, Players)
+	r.left = make([]bool, Players)
+	r.mu.Unlock()
+	go r.run(s.TickRate)
+	return r, nil
+}
+
+func (s *Server) leave(r *room, c *client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !r.started {
+		// До начала партии игрок просто освобождает место
+		for i, other := range r.clients {
+			if other == c {
+				r.clients = append(r.clients[:i], r.clients[i+1:]...)
+				break
+			}
+		}
+		for i, other := range r.clients {
+			other.slot = i
+		}
+		if len(r.clients) == 0 {
+			if s.waiting == r {
+				s.waiting = nil
+			} else {
+				delete(s.rooms, r.name)
+			}
+		}
+		log.Printf("Player '%s' left room %q before the start", c.name, r.name)
+		return
+	}
+	r.mu.Lock()
+	r.inputs[c.slot] = 0
+	r.left[c.slot] = true
+	r.mu.Unlock()
+	log.Printf("Player '%s' left room %q", c.name, r.name)
+}
+
+type room struct {
+	name    string
+	clients []*client
+	started bool
+	mu      sync.Mutex // Защищает inputs и left
+	inputs  []sim.Input
+	left    []bool
+}
+
+// setInput запоминает ввод игрока до следующего тика. Ввод, пришедший
+// до начала партии, отбрасывается.
+func (r *room) setInput(c *client, in sim.Input) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.inputs == nil {
+		return
+	}
+	r.inputs[c.slot] = in & (sim.InputLeft | sim.InputRight)
+}
+
+// run ведёт партию: каждый тик применяет последний ввод игроков и
+// рассылает снимок. Запись партии рассылается в конце, чтобы клиенты
+// отправили результат на игровой сервер.
+func (r *room) run(tickRate time.Duration) {
+	names := make([]string, Players)
+	seeds := make([]int64, Players)
+	tickets := make([]int64, Players)
+	for i, c := range r.clients {
+		names[i] = c.name
+		seeds[i], tickets[i] = c.ticket.Seed, c.ticket.Nonce
+	}
+	seed := sim.CombineSeeds(seeds...)
+	world := sim.NewMultiplayerWorld(seed, Players)
+	rec := sim.NewMultiplayerRecording(seed, Players)
+	for _, c := range r.clients {
+		c.post(ServerMessage{Type: MsgStart, Slot: c.slot, Players: names, Seed: seed, Tickets: tickets})
+	}
+	log.Printf("Room %q started: %v", r.name, names)
+
+	ticker := time.NewTicker(tickRate)
+	defer ticker.Stop()
+	inputs := make([]sim.Input, Players)
+	announced := make([]bool, Players)
+	for range ticker.C {
+		r.mu.Lock()
+		copy(inputs, r.inputs)
+		gone := 0
+		for slot, left := range r.left {
+			if left {
+				gone++
+				if !announced[slot] {
+					announced[slot] = true
+					r.broadcast(ServerMessage{Type: MsgLeft, Slot: slot})
+				}
+			}
+		}
+		r.mu.Unlock()
+		if gone == Players {
+			log.Printf("Room %q abandoned at frame %d", r.name, world.Frame)
+			return
+		}
+
+		rec.Add(inputs...)
+		ev := world.Step(inputs...)
+		state := NewSnapshot(world, ev)
+		if world.GameOver || world.GameWon {
+			r.broadcast(ServerMessage{Type: MsgEnd, State: state, Replay: rec.Encode()})
+			log.Printf("Room %q finished at frame %d", r.name, world.Frame)
+			return
+		}
+		if world.Frame >= sim.MaxRecordingFrames {
+			r.broadcast(ServerMessage{Type: MsgError, Error: "game is too long"})
+			log.Printf("Room %q stopped at frame %d", r.name, world.Frame)
+			return
+		}
+		r.broadcast(ServerMessage{Type: MsgState, State: state})
+	}
+}
+
+func (r *room) broadcast(msg ServerMessage) {
+	for _, c := range r.clients {
+		c.post(msg)
+	}
+}
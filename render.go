@@ -0,0 +1,146 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"egg_catcher2/sim"
+)
+
+// Всё, что Draw рисует каждый кадр, готовится здесь один раз: новая
+// картинка в кадре игры — это выделение памяти и новая текстура.
+
+const (
+	glyphWidth  = 6 // Размер символа отладочного шрифта ebitenutil
+	glyphHeight = 16
+	chuteLength = 95 // Жёлоб от курицы на 67.5 вниз и в сторону
+	chuteHeight = 10
+)
+
+// glyphs — атлас отладочного шрифта: те же символы, что печатает
+// ebitenutil.DebugPrintAt, но их можно рисовать из []byte и с масштабом,
+// без промежуточной картинки на весь экран.
+var glyphs = func() [256]*ebiten.Image {
+	var glyphs [256]*ebiten.Image
+	atlas := ebiten.NewImage(len(glyphs)*glyphWidth, glyphHeight)
+	for c := range glyphs {
+		// DebugPrintAt сдвигает текст на пиксель вправо
+		ebitenutil.DebugPrintAt(atlas, string(rune(c)), c*glyphWidth-1, 0)
+		glyphs[c] = atlas.SubImage(image.Rect(c*glyphWidth, 0, (c+1)*glyphWidth, glyphHeight)).(*ebiten.Image)
+	}
+	return glyphs
+}()
+
+var glyphOp ebiten.DrawImageOptions
+
+// drawText печатает строку s там же, где её напечатал бы DebugPrintAt в
+// точке (x, y), увеличив символы в scale раз.
+func drawText(dst *ebiten.Image, s string, x, y, scale float64) {
+	i := 0
+	for _, r := range s {
+		drawGlyph(dst, r, i, x, y, scale)
+		i++
+	}
+}
+
+// drawTextBytes — drawText для строки, собранной в переиспользуемом
+// буфере.
+func drawTextBytes(dst *ebiten.Image, b []byte, x, y, scale float64) {
+	for i := 0; len(b) > 0; i++ {
+		r, n := utf8.DecodeRune(b)
+		b = b[n:]
+		drawGlyph(dst, r, i, x, y, scale)
+	}
+}
+
+func drawGlyph(dst *ebiten.Image, r rune, i int, x, y, scale float64) {
+	if r < 0 || int(r) >= len(glyphs) {
+		r = '?'
+	}
+	glyphOp.GeoM.Reset()
+	glyphOp.GeoM.Translate(float64(1+i*glyphWidth), 0)
+	glyphOp.GeoM.Scale(scale, scale)
+	glyphOp.GeoM.Translate(x, y)
+	dst.DrawImage(glyphs[r], &glyphOp)
+}
+
+var rectOp ebiten.DrawImageOptions
+
+// fillRect заменяет ebitenutil.DrawRect в кадре игры: растянутый
+// whitePixel не строит векторный путь, а цвет не приводится к
+// color.Color, и ни то ни другое не выделяет память.
+func fillRect(dst *ebiten.Image, x, y, w, h float64, clr color.RGBA) {
+	rectOp.GeoM.Reset()
+	rectOp.GeoM.Scale(w, h)
+	rectOp.GeoM.Translate(x, y)
+	rectOp.ColorScale.Reset()
+	scaleWithRGBA(&rectOp.ColorScale, clr)
+	dst.DrawImage(whitePixel, &rectOp)
+}
+
+// scaleWithRGBA — ColorScale.ScaleWithColor для color.RGBA без приведения
+// к интерфейсу.
+func scaleWithRGBA(cs *ebiten.ColorScale, clr color.RGBA) {
+	cs.Scale(float32(clr.R)/0xff, float32(clr.G)/0xff, float32(clr.B)/0xff, float32(clr.A)/0xff)
+}
+
+func appendInt(b []byte, n int) []byte {
+	return strconv.AppendInt(b, int64(n), 10)
+}
+
+// textLayerImg — общий слой экранов, текст которых рисуется увеличенным.
+// За кадр слой рисуется на экран раньше, чем понадобится снова, поэтому
+// одной картинки хватает всем экранам.
+var textLayerImg = ebiten.NewImage(screenWidth, screenHeight)
+
+// textLayer очищает и возвращает общий слой для текста.
+func textLayer() *ebiten.Image {
+	textLayerImg.Clear()
+	return textLayerImg
+}
+
+var chuteImg = func() *ebiten.Image {
+	img := ebiten.NewImage(chuteLength, chuteHeight)
+	img.Fill(color.RGBA{160, 82, 45, 255})
+	return img
+}()
+
+// Квадраты вместо яиц, когда картинки не загрузились: по Value для
+// обычных яиц и по эффекту для бонусов.
+var (
+	fallbackEggs = [3]*ebiten.Image{
+		newFallbackEgg(color.RGBA{150, 75, 0, 255}),
+		newFallbackEgg(color.RGBA{255, 220, 0, 255}),
+		newFallbackEgg(color.RGBA{255, 255, 255, 255}),
+	}
+	fallbackEffectEggs = func() [sim.EffectCount]*ebiten.Image {
+		var eggs [sim.EffectCount]*ebiten.Image
+		for e := sim.EffectNone + 1; e < sim.EffectCount; e++ {
+			eggs[e] = newFallbackEgg(effectColors[e])
+		}
+		return eggs
+	}()
+)
+
+func newFallbackEgg(clr color.RGBA) *ebiten.Image {
+	img := ebiten.NewImage(eggSize, eggSize)
+	img.Fill(color.Black)
+	vector.DrawFilledRect(img, 1, 1, eggSize-2, eggSize-2, clr, false)
+	return img
+}
+
+func fallbackEgg(egg sim.Egg) *ebiten.Image {
+	if egg.Effect != sim.EffectNone {
+		return fallbackEffectEggs[egg.Effect]
+	}
+	if egg.Value == 0 || egg.Value == 2 {
+		return fallbackEggs[egg.Value]
+	}
+	return fallbackEggs[1]
+}
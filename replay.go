@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"egg_catcher2/sim"
+)
+
+// InputSource выдаёт ввод для очередного кадра симуляции: с клавиатуры
+// во время игры или из записи при просмотре повтора.
+type InputSource interface {
+	// Next возвращает ввод всех игроков кадра или false, когда ввод
+	// закончился.
+	Next() ([]sim.Input, bool)
+}
+
+type keyboardInputSource struct {
+	inputs []sim.Input // По элементу на игрока, переиспользуется между кадрами
+}
+
+func newKeyboardInputSource(players int) *keyboardInputSource {
+	return &keyboardInputSource{inputs: make([]sim.Input, players)}
+}
+
+func (s *keyboardInputSource) Next() ([]sim.Input, bool) {
+	for i := range s.inputs {
+		s.inputs[i] = keyboardInput(i)
+	}
+	return s.inputs, true
+}
+
+type recordingInputSource struct {
+	rec *sim.Recording
+	pos int
+}
+
+func (s *recordingInputSource) Next() ([]sim.Input, bool) {
+	if s.pos >= s.rec.Frames() {
+		return nil, false
+	}
+	inputs := s.rec.Frame(s.pos)
+	s.pos++
+	return inputs, true
+}
+
+type replayState struct {
+	rec      *sim.Recording
+	paused   bool
+	speed    int  // Кадров симуляции за кадр игры: 1, 2 или 4
+	finished bool // Запись закончилась раньше конца игры
+}
+
+func NewReplayGame(rec *sim.Recording, loseHeartPlayer, gainHeartPlayer, scoreHeartPlayer, bossMusic, bossHitEffect *audio.Player) *Game {
+	g := newGame(rec.Seed, 0, loseHeartPlayer, gainHeartPlayer, scoreHeartPlayer, bossMusic, bossHitEffect)
+	g.World = sim.NewStageWorld(rec.Seed, rec.Players, rec.Stage)
+	g.World.Difficulty = rec.Difficulty
+	g.recording = nil
+	g.input = &recordingInputSource{rec: rec}
+	g.replay = &replayState{rec: rec, speed: 1}
+	g.achievements = nil
+	return g
+}
+
+func (g *Game) updateReplay() error {
+	r := g.replay
+	if inpututil.IsKeyJustPressed(ebiten.KeyQ) {
+		return ebiten.Termination
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyR) {
+		*g = *NewReplayGame(r.rec, g.loseHeartPlayer, g.gainHeartPlayer, g.scoreHeartPlayer, g.bossMusic, g.bossHitEffect)
+		return nil
+	}
+	if g.Over() {
+		cx, cy := ebiten.CursorPosition()
+		mx, my := float64(cx), float64(cy)
+		g.playagainButton.hovered = g.playagainButton.IsInside(mx, my)
+		g.quitButton.hovered = g.quitButton.IsInside(mx, my)
+		if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+			if g.playagainButton.hovered {
+				*g = *NewReplayGame(r.rec, g.loseHeartPlayer, g.gainHeartPlayer, g.scoreHeartPlayer, g.bossMusic, g.bossHitEffect)
+			} else if g.quitButton.hovered {
+				return ebiten.Termination
+			}
+		}
+		return nil
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+		r.paused = !r.paused
+	}
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.Key1):
+		r.speed = 1
+	case inpututil.IsKeyJustPressed(ebiten.Key2):
+		r.speed = 2
+	case inpututil.IsKeyJustPressed(ebiten.Key4):
+		r.speed = 4
+	}
+
+	steps := r.speed
+	if r.paused {
+		steps = 0
+		// Покадровый просмотр
+		if inpututil.IsKeyJustPressed(ebiten.KeyRight) {
+			steps = 1
+		}
+	}
+	for i := 0; i < steps && !g.Over() && !r.finished; i++ {
+		inputs, ok := g.input.Next()
+		if !ok {
+			r.finished = true
+			break
+		}
+		g.step(inputs)
+	}
+	return nil
+}
+
+func (g *Game) drawReplayHUD(screen *ebiten.Image) {
+	r := g.replay
+	b := appendInt(append(g.text[:0], "REPLAY "...), r.speed)
+	b = appendInt(append(b, "x  frame "...), g.Frame)
+	b = appendInt(append(b, '/'), r.rec.Frames())
+	if r.paused {
+		b = append(b, "  PAUSED"...)
+	}
+	if r.finished {
+		b = append(b, "  END"...)
+	}
+	g.text = b
+	fillRect(screen, 0, screenHeight-36, screenWidth, 36, color.RGBA{0, 0, 0, 160})
+	drawTextBytes(screen, b, 10, screenHeight-34, 1)
+	ebitenutil.DebugPrintAt(screen, "Space pause  Right step  1/2/4 speed  R restart  Q quit", 10, screenHeight-18)
+}
+
+func replayDir() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "replays")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create replay directory: %v", err)
+	}
+	return dir, nil
+}
+
+func saveReplayFile(rec *sim.Recording) (string, error) {
+	dir, err := replayDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "replay-"+time.Now().Format("20060102-150405")+".eggr")
+	if err := os.WriteFile(path, rec.Encode(), 0o600); err != nil {
+		return "", fmt.Errorf("failed to save replay: %v", err)
+	}
+	return path, nil
+}
+
+func loadReplayFile(path string) (*sim.Recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replay: %v", err)
+	}
+	rec, err := sim.DecodeRecording(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode replay %s: %v", path, err)
+	}
+	log.Printf("Loaded replay %s: seed %d, %d players, %d frames", path, rec.Seed, rec.Players, rec.Frames())
+	return rec, nil
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"egg_catcher2/api"
+	"egg_catcher2/sim"
+)
+
+// savedGame — слот сохранения прерванной партии. Состояние мира не
+// сериализуется: генератор случайных чисел сохранить нельзя, поэтому
+// партия восстанавливается повтором записи ввода с того же seed, и мир
+// получается ровно тем же, вместе с состоянием генератора. Tickets —
+// билет партии, по которому сервер примет результат. Score, Level и
+// Stage нужны только для экрана продолжения.
+type savedGame struct {
+	Replay  []byte              `json:"replay"`
+	Tickets []int64             `json:"tickets"`
+	Daily   *api.DailyChallenge `json:"daily,omitempty"`
+	Score   int                 `json:"score"`
+	Level   int                 `json:"level"`
+	Stage   int                 `json:"stage"`
+	SavedAt time.Time           `json:"saved_at"`
+}
+
+// saveFile — слот своего игрока, чтобы партию не продолжил другой
+// аккаунт на том же компьютере.
+func saveFile(playerID int) (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "save-"+strconv.Itoa(playerID)+".json"), nil
+}
+
+// canSave сообщает, можно ли сохранить партию: партии на двоих, сетевые
+// и просмотр записи не сохраняются, а пустая партия не должна затереть
+// слот, который игрок ещё не успел продолжить.
+func (g *Game) canSave() bool {
+	return g.recording != nil && g.recording.Frames() > 0 && g.replay == nil && g.partner == nil && g.online == nil && !g.Over()
+}
+
+// saveSlot записывает партию в слот игрока.
+func (g *Game) saveSlot() error {
+	if !g.canSave() {
+		return nil
+	}
+	path, err := saveFile(g.playerID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(savedGame{
+		Replay:  g.recording.Encode(),
+		Tickets: g.tickets,
+		Daily:   g.daily,
+		Score:   g.Wolves[0].Score,
+		Level:   g.Level,
+		Stage:   g.Stage,
+		SavedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode saved game: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save game: %v", err)
+	}
+	log.Printf("Saved game at frame %d to %s", g.recording.Frames(), path)
+	return nil
+}
+
+// loadSlot возвращает сохранённую партию игрока или nil, если её нет.
+func loadSlot(playerID int) (*savedGame, error) {
+	path, err := saveFile(playerID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read saved game: %v", err)
+	}
+	var s savedGame
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse saved game: %v", err)
+	}
+	return &s, nil
+}
+
+// removeSlot удаляет слот игрока. Слот живёт, только пока партия стоит
+// на паузе или закрыта: иначе можно было бы переигрывать неудачные
+// моменты, возвращаясь к сохранению.
+func removeSlot(playerID int) {
+	path, err := saveFile(playerID)
+	if err != nil {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Error removing saved game: %v", err)
+	}
+}
+
+// resume восстанавливает сохранённую партию. Она продолжается с паузы,
+// чтобы игрок успел осмотреться; музыка приглушена, как на паузе.
+func (g *Game) resume(s *savedGame) error {
+	rec, err := sim.DecodeRecording(s.Replay)
+	if err != nil {
+		return fmt.Errorf("invalid saved game: %v", err)
+	}
+	if rec.Players != 1 {
+		return fmt.Errorf("invalid saved game: %d players", rec.Players)
+	}
+	w, err := sim.Replay(rec)
+	if err != nil {
+		return fmt.Errorf("invalid saved game: %v", err)
+	}
+	if w.Over() {
+		return errors.New("saved game is already over")
+	}
+	*g = *newGame(rec.Seed, g.playerID, g.loseHeartPlayer, g.gainHeartPlayer, g.scoreHeartPlayer, g.bossMusic, g.bossHitEffect)
+	g.World = w
+	g.recording = rec
+	g.tickets = s.Tickets
+	g.daily = s.Daily
+	g.isPaused = true
+	applyMusicVolume(true)
+	if g.InBossRoom {
+		if player != nil {
+			player.Pause()
+		}
+		playSound(g.bossMusic, "boss music")
+	}
+	log.Printf("Resumed saved game at frame %d", rec.Frames())
+	return nil
+}
+
+// ContinueState предлагает после входа продолжить сохранённую партию
+// или начать новую.
+type ContinueState struct {
+	playerID       int
+	save           *savedGame
+	continueButton Button
+	newGameButton  Button
+	resume         bool // Продолжить сохранённую партию
+	done           bool
+}
+
+func NewContinueState(playerID int, save *savedGame) *ContinueState {
+	return &ContinueState{
+		playerID: playerID,
+		save:     save,
+		continueButton: Button{
+			x:     screenWidth/3 - buttonWidth - 10,
+			y:     screenHeight/3 + 20,
+			w:     buttonWidth,
+			h:     buttonHeight,
+			label: "Continue",
+		},
+		newGameButton: Button{
+			x:     screenWidth/3 + 10,
+			y:     screenHeight/3 + 20,
+			w:     buttonWidth,
+			h:     buttonHeight,
+			label: "New Game",
+		},
+	}
+}
+
+func (s *ContinueState) Update() error {
+	cx, cy := ebiten.CursorPosition()
+	mx, my := float64(cx), float64(cy)
+	s.continueButton.hovered = s.continueButton.IsInside(mx, my)
+	s.newGameButton.hovered = s.newGameButton.IsInside(mx, my)
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		if s.continueButton.hovered {
+			s.resume = true
+		} else if s.newGameButton.hovered {
+			removeSlot(s.playerID)
+			s.done = true
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		s.resume = true
+	}
+	return nil
+}
+
+func (s *ContinueState) Draw(screen *ebiten.Image) {
+	if imgBackgroundMenu != nil {
+		screen.DrawImage(imgBackgroundMenu, nil)
+	} else {
+		screen.Fill(color.RGBA{0, 128, 255, 255})
+	}
+
+	textImg := textLayer()
+	ebitenutil.DebugPrintAt(textImg, "You have an unfinished game", screenWidth/3-90, screenHeight/3-100)
+	mode := fmt.Sprintf("Level %d", s.save.Level)
+	if s.save.Stage > 0 && s.save.Stage <= len(sim.Stages) {
+		mode = fmt.Sprintf("Stage %d: %s", s.save.Stage, sim.Stages[s.save.Stage-1].Name)
+	} else if s.save.Daily != nil {
+		mode = "Daily Challenge " + s.save.Daily.Day
+	}
+	ebitenutil.DebugPrintAt(textImg, fmt.Sprintf("Score: %d, %s", s.save.Score, mode), screenWidth/3-90, screenHeight/3-70)
+	ebitenutil.DebugPrintAt(textImg, "Saved "+s.save.SavedAt.Local().Format("2006-01-02 15:04"), screenWidth/3-90, screenHeight/3-50)
+	s.drawButton(textImg, &s.continueButton)
+	s.drawButton(textImg, &s.newGameButton)
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(1.5, 1.5)
+	screen.DrawImage(textImg, op)
+}
+
+func (s *ContinueState) drawButton(screen *ebiten.Image, b *Button) {
+	buttonColor := color.RGBA{0, 128, 255, 255}
+	if b.hovered {
+		buttonColor = color.RGBA{0, 192, 255, 255}
+	}
+	ebitenutil.DrawRect(screen, b.x, b.y, b.w, b.h, buttonColor)
+	ebitenutil.DebugPrintAt(screen, b.label, int(b.x+(b.w-float64(len(b.label)*7))/2), int(b.y+b.h/2))
+}
+
+func (s *ContinueState) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return screenWidth, screenHeight
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"log"
+
+	"egg_catcher2/achievement"
+	"egg_catcher2/api"
+	"egg_catcher2/sim"
+	"egg_catcher2/store"
+)
+
+// gameTracker собирает достижения, полученные в проверяемой записи.
+type gameTracker struct {
+	*achievement.Tracker
+	earned []string
+}
+
+func (t *gameTracker) handle(e sim.Event) {
+	for _, a := range t.Handle(e) {
+		t.earned = append(t.earned, a.ID)
+	}
+}
+
+// achievementTracker готовит проверку достижений игрока slot с учётом
+// яиц, пойманных в прошлых партиях, и уже полученных достижений.
+func (s *Service) achievementTracker(p store.Player, slot int) (*gameTracker, error) {
+	eggs, err := s.store.EggsCaught(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	unlocked, err := s.store.Achievements(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(unlocked))
+	for _, a := range unlocked {
+		ids = append(ids, a.ID)
+	}
+	return &gameTracker{Tracker: achievement.NewTracker(slot, eggs, ids)}, nil
+}
+
+// unlockAchievements засчитывает достижения из уже принятой партии.
+func (s *Service) unlockAchievements(p store.Player, t *gameTracker) error {
+	if len(t.earned) == 0 {
+		return nil
+	}
+	if err := s.store.UnlockAchievements(p.ID, t.earned, s.now()); err != nil {
+		return err
+	}
+	log.Printf("Player '%s' with ID %d earned achievements %v", p.Name, p.ID, t.earned)
+	return nil
+}
+
+func (s *Service) Achievements(token string) (api.Achievements, error) {
+	p, err := s.Authorize(token)
+	if err != nil {
+		return api.Achievements{}, err
+	}
+	unlocked, err := s.store.Achievements(p.ID)
+	if err != nil {
+		return api.Achievements{}, err
+	}
+	eggs, err := s.store.EggsCaught(p.ID)
+	if err != nil {
+		return api.Achievements{}, err
+	}
+	result := api.Achievements{Unlocked: make([]api.Achievement, 0, len(unlocked)), EggsCaught: eggs}
+	for _, a := range unlocked {
+		result.Unlocked = append(result.Unlocked, api.Achievement{ID: a.ID, UnlockedAt: a.UnlockedAt})
+	}
+	return result, nil
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"egg_catcher2/api"
+	"egg_catcher2/store"
+)
+
+func (s *Service) Campaign(token string) (api.Campaign, error) {
+	p, err := s.Authorize(token)
+	if err != nil {
+		return api.Campaign{}, err
+	}
+	completed, err := s.store.CampaignStage(p.ID)
+	if err != nil {
+		return api.Campaign{}, err
+	}
+	return api.Campaign{Completed: completed}, nil
+}
+
+// checkStage принимает партию этапа кампании, только если этап открыт:
+// пройден предыдущий.
+func (s *Service) checkStage(p store.Player, stage int) error {
+	if stage == 0 {
+		return nil
+	}
+	completed, err := s.store.CampaignStage(p.ID)
+	if err != nil {
+		return err
+	}
+	if stage > completed+1 {
+		return &api.Error{Status: http.StatusForbidden, Message: fmt.Sprintf("campaign stage %d is locked", stage)}
+	}
+	return nil
+}
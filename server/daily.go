@@ -0,0 +1,107 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"egg_catcher2/api"
+	"egg_catcher2/sim"
+	"egg_catcher2/store"
+)
+
+const dayFormat = "2006-01-02"
+
+// SetDailySecret задаёт секрет, из которого выводится seed ежедневного
+// испытания. Без него seed можно вычислить заранее и потренироваться.
+func (s *Service) SetDailySecret(secret string) {
+	s.dailySecret = secret
+}
+
+func (s *Service) today() string {
+	return s.now().UTC().Format(dayFormat)
+}
+
+func (s *Service) dailySeed(day string) int64 {
+	sum := sha256.Sum256([]byte(s.dailySecret + ":" + day))
+	return int64(binary.BigEndian.Uint64(sum[:8]))
+}
+
+// StartDaily засчитывает попытку сразу, чтобы нельзя было бросать
+// неудачные партии и начинать заново.
+func (s *Service) StartDaily(token string) (api.DailyChallenge, error) {
+	p, err := s.Authorize(token)
+	if err != nil {
+		return api.DailyChallenge{}, err
+	}
+	day := s.today()
+	err = s.store.StartDaily(p.ID, day)
+	if errors.Is(err, store.ErrAlreadyPlayed) {
+		return api.DailyChallenge{}, &api.Error{Status: http.StatusConflict, Message: "daily challenge already played today"}
+	}
+	if err != nil {
+		return api.DailyChallenge{}, err
+	}
+	log.Printf("Player '%s' with ID %d started daily challenge %s", p.Name, p.ID, day)
+	return api.DailyChallenge{Day: day, Seed: s.dailySeed(day)}, nil
+}
+
+func (s *Service) SubmitDaily(token string, result api.GameResult) error {
+	p, err := s.Authorize(token)
+	if err != nil {
+		return err
+	}
+	day, err := s.store.PendingDaily(p.ID)
+	if errors.Is(err, store.ErrNotFound) {
+		return &api.Error{Status: http.StatusConflict, Message: "no daily challenge in progress"}
+	}
+	if err != nil {
+		return err
+	}
+	rec, err := sim.DecodeRecording(result.Replay)
+	if err != nil {
+		return badRequest("invalid replay: %v", err)
+	}
+	if rec.Seed != s.dailySeed(day) || rec.Players != 1 || rec.Stage != 0 || rec.Difficulty != sim.DifficultyNormal {
+		return &api.Error{Status: http.StatusUnprocessableEntity, Message: "replay is not from the daily challenge"}
+	}
+	tracker, err := s.achievementTracker(p, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := verifyReplay(result, tracker); err != nil {
+		log.Printf("Rejected daily result from player '%s' with ID %d: %v", p.Name, p.ID, err)
+		return err
+	}
+	if err := s.store.FinishDaily(p.ID, day, result.Score, result.Lives, result.Replay); err != nil {
+		return err
+	}
+	if err := s.unlockAchievements(p, tracker); err != nil {
+		return err
+	}
+	log.Printf("Player '%s' with ID %d scored %d in daily challenge %s", p.Name, p.ID, result.Score, day)
+	return nil
+}
+
+func (s *Service) DailyLeaderboard(day string, limit int) ([]api.DailyScore, error) {
+	if day == "" {
+		day = s.today()
+	} else if _, err := time.Parse(dayFormat, day); err != nil {
+		return nil, badRequest("invalid day %q", day)
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 5
+	}
+	scores, err := s.store.DailyLeaderboard(day, limit)
+	if err != nil {
+		return nil, err
+	}
+	leaderboard := make([]api.DailyScore, 0, len(scores))
+	for _, d := range scores {
+		leaderboard = append(leaderboard, api.DailyScore{Name: d.Name, Score: d.Score})
+	}
+	return leaderboard, nil
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"egg_catcher2/api"
+)
+
+const maxRequestBody = 1 << 20
+
+// NewHandler возвращает HTTP API сервера. Все ответы — JSON, ошибки
+// передаются как api.Error.
+func NewHandler(s *Service) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/register", func(w http.ResponseWriter, r *http.Request) {
+		var req api.Credentials
+		if !decode(w, r, &req) {
+			return
+		}
+		sess, err := s.Register(req.Name, req.Password, req.Remember)
+		respond(w, sess, err)
+	})
+	mux.HandleFunc("POST /api/login", func(w http.ResponseWriter, r *http.Request) {
+		var req api.Credentials
+		if !decode(w, r, &req) {
+			return
+		}
+		sess, err := s.Login(req.Name, req.Password, clientAddr(r), req.Remember)
+		respond(w, sess, err)
+	})
+	mux.HandleFunc("GET /api/profile", func(w http.ResponseWriter, r *http.Request) {
+		p, err := s.Profile(bearerToken(r))
+		respond(w, p, err)
+	})
+	mux.HandleFunc("POST /api/logout", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, nil, s.Logout(bearerToken(r)))
+	})
+	mux.HandleFunc("POST /api/logout-all", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, nil, s.LogoutAll(bearerToken(r)))
+	})
+	mux.HandleFunc("POST /api/password", func(w http.ResponseWriter, r *http.Request) {
+		var req api.PasswordChange
+		if !decode(w, r, &req) {
+			return
+		}
+		respond(w, nil, s.ChangePassword(bearerToken(r), req.OldPassword, req.NewPassword))
+	})
+	mux.HandleFunc("POST /api/account/delete", func(w http.ResponseWriter, r *http.Request) {
+		var req api.PasswordConfirm
+		if !decode(w, r, &req) {
+			return
+		}
+		respond(w, nil, s.DeleteAccount(bearerToken(r), req.Password))
+	})
+	mux.HandleFunc("POST /api/games/start", func(w http.ResponseWriter, r *http.Request) {
+		var req api.GameStart
+		if !decode(w, r, &req) {
+			return
+		}
+		ticket, err := s.StartGame(bearerToken(r), req.Ghost)
+		respond(w, ticket, err)
+	})
+	mux.HandleFunc("POST /api/games", func(w http.ResponseWriter, r *http.Request) {
+		var req api.GameResult
+		if !decode(w, r, &req) {
+			return
+		}
+		p, err := s.SubmitGame(bearerToken(r), req)
+		respond(w, p, err)
+	})
+	mux.HandleFunc("GET /api/best-run", func(w http.ResponseWriter, r *http.Request) {
+		run, err := s.BestRun(bearerToken(r))
+		respond(w, run, err)
+	})
+	mux.HandleFunc("GET /api/achievements", func(w http.ResponseWriter, r *http.Request) {
+		a, err := s.Achievements(bearerToken(r))
+		respond(w, a, err)
+	})
+	mux.HandleFunc("GET /api/stats", func(w http.ResponseWriter, r *http.Request) {
+		st, err := s.Stats(bearerToken(r))
+		respond(w, st, err)
+	})
+	mux.HandleFunc("GET /api/campaign", func(w http.ResponseWriter, r *http.Request) {
+		campaign, err := s.Campaign(bearerToken(r))
+		respond(w, campaign, err)
+	})
+	mux.HandleFunc("POST /api/daily/start", func(w http.ResponseWriter, r *http.Request) {
+		daily, err := s.StartDaily(bearerToken(r))
+		respond(w, daily, err)
+	})
+	mux.HandleFunc("POST /api/daily/submit", func(w http.ResponseWriter, r *http.Request) {
+		var req api.GameResult
+		if !decode(w, r, &req) {
+			return
+		}
+		respond(w, nil, s.SubmitDaily(bearerToken(r), req))
+	})
+	mux.HandleFunc("GET /api/daily/leaderboard", func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		leaderboard, err := s.DailyLeaderboard(r.URL.Query().Get("day"), limit)
+		respond(w, leaderboard, err)
+	})
+	mux.HandleFunc("GET /api/leaderboard", func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		leaderboard, err := s.Leaderboard(limit)
+		respond(w, leaderboard, err)
+	})
+	return mux
+}
+
+func bearerToken(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// clientAddr — ключ ограничителя входа для клиента: адрес соединения.
+// Ничему из запроса, что выбирает сам клиент, здесь верить нельзя.
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(v); err != nil {
+		writeJSON(w, http.StatusBadRequest, &api.Error{Message: "invalid request body"})
+		return false
+	}
+	return true
+}
+
+func respond(w http.ResponseWriter, v any, err error) {
+	if err != nil {
+		var apiErr *api.Error
+		if !errors.As(err, &apiErr) {
+			log.Printf("Internal error: %v", err)
+			apiErr = &api.Error{Status: http.StatusInternalServerError, Message: "internal server error"}
+		}
+		if apiErr.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(apiErr.RetryAfter))
+		}
+		writeJSON(w, apiErr.Status, apiErr)
+		return
+	}
+	if v == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,362 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"egg_catcher2/api"
+	"egg_catcher2/sim"
+	"egg_catcher2/store"
+)
+
+const testPassword = "secret123"
+
+// testServer — сервер поверх хранилища в памяти. Часы ограничителя
+// входа двигает тест, чтобы не ждать задержек.
+type testServer struct {
+	*httptest.Server
+	service *Service
+	now     time.Time
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	ts := &testServer{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	ts.service = NewService(store.NewMemory())
+	ts.service.limiter.Now = func() time.Time { return ts.now }
+	ts.Server = httptest.NewServer(NewHandler(ts.service))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// do отправляет запрос и разбирает ответ в out, если он не nil.
+func (ts *testServer) do(t *testing.T, method, path, token string, body, out any) *http.Response {
+	t.Helper()
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, ts.URL+path, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: failed to decode response: %v", method, path, err)
+		}
+	}
+	return resp
+}
+
+func (ts *testServer) register(t *testing.T, name string) api.Session {
+	t.Helper()
+	var sess api.Session
+	resp := ts.do(t, http.MethodPost, "/api/register", "", api.Credentials{Name: name, Password: testPassword}, &sess)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("register %s: status %d", name, resp.StatusCode)
+	}
+	return sess
+}
+
+func (ts *testServer) startGame(t *testing.T, token string) api.GameTicket {
+	t.Helper()
+	var ticket api.GameTicket
+	resp := ts.do(t, http.MethodPost, "/api/games/start", token, api.GameStart{}, &ticket)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("start game: status %d", resp.StatusCode)
+	}
+	return ticket
+}
+
+// playGame доигрывает партию игроков по их билетам до конца на
+// автопилоте и возвращает результат игрока slot.
+func playGame(slot int, tickets ...api.GameTicket) api.GameResult {
+	seeds := make([]int64, len(tickets))
+	nonces := make([]int64, len(tickets))
+	for i, t := range tickets {
+		seeds[i], nonces[i] = t.Seed, t.Nonce
+	}
+	seed := sim.CombineSeeds(seeds...)
+	w := sim.NewMultiplayerWorld(seed, len(tickets))
+	rec := sim.NewMultiplayerRecording(seed, len(tickets))
+	inputs := make([]sim.Input, len(tickets))
+	for !w.Over() {
+		for i := range inputs {
+			inputs[i] = w.Autopilot(i)
+		}
+		rec.Add(inputs...)
+		w.Step(inputs...)
+	}
+	wolf := w.Wolves[slot]
+	return api.GameResult{Score: wolf.Score, Lives: wolf.Lives, BestCombo: wolf.BestCombo, Replay: rec.Encode(), Slot: slot, Tickets: nonces}
+}
+
+// submitNewGame играет одиночную партию по новому билету и отправляет её.
+func (ts *testServer) submitNewGame(t *testing.T, token string) api.GameResult {
+	t.Helper()
+	result := playGame(0, ts.startGame(t, token))
+	if resp := ts.do(t, http.MethodPost, "/api/games", token, result, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("submit: status %d", resp.StatusCode)
+	}
+	return result
+}
+
+func TestRegister(t *testing.T) {
+	ts := newTestServer(t)
+	sess := ts.register(t, "alice")
+	if sess.Token == "" || sess.Player.Name != "alice" || sess.Player.ID == 0 {
+		t.Fatalf("unexpected session %+v", sess)
+	}
+
+	var apiErr api.Error
+	resp := ts.do(t, http.MethodPost, "/api/register", "", api.Credentials{Name: "alice", Password: testPassword}, &apiErr)
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("duplicate name: status %d, want %d", resp.StatusCode, http.StatusConflict)
+	}
+	resp = ts.do(t, http.MethodPost, "/api/register", "", api.Credentials{Name: "bob", Password: "short"}, &apiErr)
+	if resp.StatusCode != http.StatusBadRequest || apiErr.Message == "" {
+		t.Fatalf("weak password: status %d, error %q", resp.StatusCode, apiErr.Message)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	ts := newTestServer(t)
+	ts.register(t, "alice")
+
+	var sess api.Session
+	resp := ts.do(t, http.MethodPost, "/api/login", "", api.Credentials{Name: "alice", Password: testPassword}, &sess)
+	if resp.StatusCode != http.StatusOK || sess.Player.Name != "alice" {
+		t.Fatalf("login: status %d, session %+v", resp.StatusCode, sess)
+	}
+}
+
+func TestLoginErrorsDoNotRevealUsers(t *testing.T) {
+	ts := newTestServer(t)
+	ts.register(t, "alice")
+
+	var unknown, wrong api.Error
+	resp := ts.do(t, http.MethodPost, "/api/login", "", api.Credentials{Name: "nobody", Password: testPassword}, &unknown)
+	unknownStatus := resp.StatusCode
+	ts.now = ts.now.Add(time.Minute)
+	resp = ts.do(t, http.MethodPost, "/api/login", "", api.Credentials{Name: "alice", Password: "wrong1234"}, &wrong)
+	if unknownStatus != http.StatusUnauthorized || resp.StatusCode != unknownStatus || unknown != wrong {
+		t.Fatalf("unknown user: %d %+v, bad password: %d %+v", unknownStatus, unknown, resp.StatusCode, wrong)
+	}
+}
+
+func TestLoginThrottled(t *testing.T) {
+	ts := newTestServer(t)
+	ts.register(t, "alice")
+
+	bad := api.Credentials{Name: "alice", Password: "wrong1234"}
+	if resp := ts.do(t, http.MethodPost, "/api/login", "", bad, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("first attempt: status %d", resp.StatusCode)
+	}
+	var apiErr api.Error
+	good := api.Credentials{Name: "alice", Password: testPassword}
+	resp := ts.do(t, http.MethodPost, "/api/login", "", good, &apiErr)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("attempt during backoff: status %d, want %d", resp.StatusCode, http.StatusTooManyRequests)
+	}
+	if got := resp.Header.Get("Retry-After"); got != "1" || apiErr.RetryAfter != 1 {
+		t.Fatalf("Retry-After %q, retry_after %d, want 1", got, apiErr.RetryAfter)
+	}
+
+	ts.now = ts.now.Add(time.Second)
+	if resp := ts.do(t, http.MethodPost, "/api/login", "", good, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("attempt after backoff: status %d", resp.StatusCode)
+	}
+}
+
+// Вход в свой аккаунт не должен снимать ограничение с клиента, который
+// подбирает чужой пароль.
+func TestLoginSuccessKeepsClientCounter(t *testing.T) {
+	ts := newTestServer(t)
+	ts.register(t, "alice")
+	ts.register(t, "mallory")
+
+	for i := 0; i < ts.service.limiter.MaxFailures; i++ {
+		ts.do(t, http.MethodPost, "/api/login", "", api.Credentials{Name: "alice", Password: "guess1234"}, nil)
+		ts.now = ts.now.Add(time.Minute)
+		ts.do(t, http.MethodPost, "/api/login", "", api.Credentials{Name: "mallory", Password: testPassword}, nil)
+	}
+	resp := ts.do(t, http.MethodPost, "/api/login", "", api.Credentials{Name: "mallory", Password: testPassword}, nil)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("client after %d failures: status %d, want %d", ts.service.limiter.MaxFailures, resp.StatusCode, http.StatusTooManyRequests)
+	}
+}
+
+// Счётчик клиента привязан к адресу соединения: подбор по разным именам
+// с новым идентификатором в каждом запросе его не обходит.
+func TestLoginThrottlesClientByAddress(t *testing.T) {
+	ts := newTestServer(t)
+	ts.register(t, "alice")
+
+	for i := 0; i < ts.service.limiter.MaxFailures; i++ {
+		body := map[string]string{
+			"name":      fmt.Sprintf("user%d", i),
+			"password":  "guess1234",
+			"client_id": fmt.Sprintf("client%d", i),
+		}
+		ts.do(t, http.MethodPost, "/api/login", "", body, nil)
+		ts.now = ts.now.Add(time.Minute)
+	}
+	resp := ts.do(t, http.MethodPost, "/api/login", "", api.Credentials{Name: "alice", Password: testPassword}, nil)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("address after %d failures: status %d, want %d", ts.service.limiter.MaxFailures, resp.StatusCode, http.StatusTooManyRequests)
+	}
+}
+
+func TestSubmitGame(t *testing.T) {
+	ts := newTestServer(t)
+	sess := ts.register(t, "alice")
+	result := playGame(0, ts.startGame(t, sess.Token))
+
+	var player api.Player
+	resp := ts.do(t, http.MethodPost, "/api/games", sess.Token, result, &player)
+	if resp.StatusCode != http.StatusOK || player.HighScore != result.Score {
+		t.Fatalf("submit: status %d, player %+v, want high score %d", resp.StatusCode, player, result.Score)
+	}
+
+	cheat := playGame(0, ts.startGame(t, sess.Token))
+	cheat.Score += 10
+	if resp := ts.do(t, http.MethodPost, "/api/games", sess.Token, cheat, nil); resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("tampered score: status %d, want %d", resp.StatusCode, http.StatusUnprocessableEntity)
+	}
+	if resp := ts.do(t, http.MethodPost, "/api/games", "", result, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("no token: status %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestSubmitGameOnlyOnce(t *testing.T) {
+	ts := newTestServer(t)
+	sess := ts.register(t, "alice")
+	result := ts.submitNewGame(t, sess.Token)
+	if resp := ts.do(t, http.MethodPost, "/api/games", sess.Token, result, nil); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("resubmitted game: status %d, want %d", resp.StatusCode, http.StatusConflict)
+	}
+}
+
+func TestSubmitGameNeedsServerSeed(t *testing.T) {
+	ts := newTestServer(t)
+	sess := ts.register(t, "alice")
+	ticket := ts.startGame(t, sess.Token)
+
+	// Seed, выбранный клиентом, не совпадает с билетом
+	chosen := playGame(0, api.GameTicket{Nonce: ticket.Nonce, Seed: ticket.Seed + 1})
+	if resp := ts.do(t, http.MethodPost, "/api/games", sess.Token, chosen, nil); resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("client seed: status %d, want %d", resp.StatusCode, http.StatusUnprocessableEntity)
+	}
+	unknown := playGame(0, api.GameTicket{Nonce: ticket.Nonce + 1, Seed: ticket.Seed})
+	if resp := ts.do(t, http.MethodPost, "/api/games", sess.Token, unknown, nil); resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("unknown ticket: status %d, want %d", resp.StatusCode, http.StatusUnprocessableEntity)
+	}
+	noTicket := playGame(0, ticket)
+	noTicket.Tickets = nil
+	if resp := ts.do(t, http.MethodPost, "/api/games", sess.Token, noTicket, nil); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("no ticket: status %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	// Отклонённые попытки билет не тратят
+	if resp := ts.do(t, http.MethodPost, "/api/games", sess.Token, playGame(0, ticket), nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("valid game: status %d", resp.StatusCode)
+	}
+}
+
+func TestSubmitGameSlotBelongsToTicketOwner(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.register(t, "alice")
+	bob := ts.register(t, "bob")
+	tickets := []api.GameTicket{ts.startGame(t, alice.Token), ts.startGame(t, bob.Token)}
+
+	// Алиса не может засчитать себе результат Боба
+	if resp := ts.do(t, http.MethodPost, "/api/games", alice.Token, playGame(1, tickets...), nil); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("other player's slot: status %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+	for i, sess := range []api.Session{alice, bob} {
+		if resp := ts.do(t, http.MethodPost, "/api/games", sess.Token, playGame(i, tickets...), nil); resp.StatusCode != http.StatusOK {
+			t.Fatalf("slot %d: status %d", i, resp.StatusCode)
+		}
+	}
+
+	// Оба места по билетам одного игрока не засчитываются
+	solo := []api.GameTicket{ts.startGame(t, alice.Token), ts.startGame(t, alice.Token)}
+	if resp := ts.do(t, http.MethodPost, "/api/games", alice.Token, playGame(0, solo...), nil); resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("two slots for one player: status %d, want %d", resp.StatusCode, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestGhostTicketUsesBestRunSeed(t *testing.T) {
+	ts := newTestServer(t)
+	sess := ts.register(t, "alice")
+	if resp := ts.do(t, http.MethodPost, "/api/games/start", sess.Token, api.GameStart{Ghost: true}, nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("ghost without best run: status %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+	ticket := ts.startGame(t, sess.Token)
+	ts.do(t, http.MethodPost, "/api/games", sess.Token, playGame(0, ticket), nil)
+
+	var ghost api.GameTicket
+	ts.do(t, http.MethodPost, "/api/games/start", sess.Token, api.GameStart{Ghost: true}, &ghost)
+	if ghost.Seed != ticket.Seed || ghost.Nonce == ticket.Nonce {
+		t.Fatalf("ghost ticket %+v, best run ticket %+v", ghost, ticket)
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	ts := newTestServer(t)
+	scores := map[string]int{}
+	for _, name := range []string{"alice", "bob", "carol"} {
+		sess := ts.register(t, name)
+		scores[name] = ts.submitNewGame(t, sess.Token).Score
+	}
+
+	var leaderboard []api.Player
+	if resp := ts.do(t, http.MethodGet, "/api/leaderboard?limit=2", "", nil, &leaderboard); resp.StatusCode != http.StatusOK {
+		t.Fatalf("leaderboard: status %d", resp.StatusCode)
+	}
+	if len(leaderboard) != 2 {
+		t.Fatalf("got %d entries, want 2", len(leaderboard))
+	}
+	for i, p := range leaderboard {
+		if p.HighScore != scores[p.Name] {
+			t.Fatalf("%s: high score %d, want %d", p.Name, p.HighScore, scores[p.Name])
+		}
+		if i > 0 && p.HighScore > leaderboard[i-1].HighScore {
+			t.Fatalf("leaderboard not sorted: %+v", leaderboard)
+		}
+	}
+}
+
+func TestProfile(t *testing.T) {
+	ts := newTestServer(t)
+	sess := ts.register(t, "alice")
+	result := ts.submitNewGame(t, sess.Token)
+
+	var p api.Player
+	resp := ts.do(t, http.MethodGet, "/api/profile", sess.Token, nil, &p)
+	if resp.StatusCode != http.StatusOK || p.Name != "alice" || p.HighScore != result.Score {
+		t.Fatalf("profile: status %d, player %+v", resp.StatusCode, p)
+	}
+	if resp := ts.do(t, http.MethodGet, "/api/profile", "bogus", nil, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("bad token: status %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+	ts.do(t, http.MethodPost, "/api/logout", sess.Token, nil, nil)
+	if resp := ts.do(t, http.MethodGet, "/api/profile", sess.Token, nil, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("after logout: status %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,422 @@
+// Package server реализует игровой сервер: учётные записи, сессии,
+// сохранение партий и таблицу рекордов поверх store.Store.
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"egg_catcher2/api"
+	"egg_catcher2/sim"
+	"egg_catcher2/store"
+	"egg_catcher2/throttle"
+)
+
+const (
+	sessionTTL           = 12 * time.Hour
+	persistentSessionTTL = 30 * 24 * time.Hour
+	maxUsernameLength    = 20
+	recentGames          = 30 // Сколько последних партий попадает в график счёта
+)
+
+var (
+	errInvalidCredentials = &api.Error{Status: http.StatusUnauthorized, Message: "invalid username or password"}
+	errUnauthorized       = &api.Error{Status: http.StatusUnauthorized, Message: "session expired or revoked"}
+	errIncorrectPassword  = &api.Error{Status: http.StatusForbidden, Message: "incorrect password"}
+)
+
+// Хэш для несуществующих пользователей, чтобы проверка занимала одинаковое время
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("egg catcher dummy password"), bcrypt.DefaultCost)
+
+func badRequest(format string, args ...any) *api.Error {
+	return &api.Error{Status: http.StatusBadRequest, Message: fmt.Sprintf(format, args...)}
+}
+
+type Service struct {
+	store       store.Store
+	limiter     *throttle.Limiter
+	now         func() time.Time
+	dailySecret string
+}
+
+func NewService(st store.Store) *Service {
+	return &Service{
+		store:       st,
+		limiter:     throttle.NewLimiter(st.Attempts()),
+		now:         time.Now,
+		dailySecret: "egg catcher daily",
+	}
+}
+
+func validatePassword(username, password string) error {
+	if len(password) < api.MinPasswordLength {
+		return badRequest("password must be at least %d characters", api.MinPasswordLength)
+	}
+	if len(password) > api.MaxPasswordLength {
+		return badRequest("password must be at most %d characters", api.MaxPasswordLength)
+	}
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsSpace(r):
+			return badRequest("password cannot contain spaces")
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return badRequest("password must contain letters and digits")
+	}
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		return badRequest("password cannot contain the username")
+	}
+	return nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func toAPIPlayer(p store.Player) api.Player {
+	return api.Player{ID: p.ID, Name: p.Name, HighScore: p.HighScore}
+}
+
+func (s *Service) newSession(p store.Player, remember bool) (api.Session, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return api.Session{}, fmt.Errorf("failed to generate session token: %v", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	ttl := sessionTTL
+	if remember {
+		ttl = persistentSessionTTL
+	}
+	err := s.store.CreateSession(hashToken(token), store.Session{
+		PlayerID:   p.ID,
+		Persistent: remember,
+		ExpiresAt:  s.now().Add(ttl),
+	})
+	if err != nil {
+		return api.Session{}, err
+	}
+	return api.Session{Token: token, Player: toAPIPlayer(p)}, nil
+}
+
+func (s *Service) Register(name, password string, remember bool) (api.Session, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return api.Session{}, badRequest("username cannot be empty")
+	}
+	if len(name) > maxUsernameLength {
+		return api.Session{}, badRequest("username must be at most %d characters", maxUsernameLength)
+	}
+	if err := validatePassword(name, password); err != nil {
+		return api.Session{}, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return api.Session{}, fmt.Errorf("failed to hash password: %v", err)
+	}
+	p, err := s.store.CreatePlayer(name, string(hashedPassword))
+	if errors.Is(err, store.ErrNameTaken) {
+		return api.Session{}, &api.Error{Status: http.StatusConflict, Message: "username already taken"}
+	}
+	if err != nil {
+		return api.Session{}, err
+	}
+	log.Printf("Successfully registered new player '%s' with ID %d", p.Name, p.ID)
+	return s.newSession(p, remember)
+}
+
+// Login проверяет пароль с учётом ограничения попыток по имени и по клиенту.
+// Ответ не зависит от того, существует ли пользователь.
+func (s *Service) Login(name, password, client string, remember bool) (api.Session, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return api.Session{}, badRequest("username cannot be empty")
+	}
+	userKey := "user:" + strings.ToLower(name)
+	keys := []string{userKey, "client:" + client}
+	if err := s.limiter.Check(keys...); err != nil {
+		var limit *throttle.LimitError
+		if errors.As(err, &limit) {
+			return api.Session{}, &api.Error{
+				Status:     http.StatusTooManyRequests,
+				Message:    limit.Error(),
+				RetryAfter: int(math.Ceil(limit.Wait.Seconds())),
+			}
+		}
+		return api.Session{}, err
+	}
+	p, err := s.store.PlayerByName(name)
+	storedPassword := p.PasswordHash
+	if errors.Is(err, store.ErrNotFound) {
+		storedPassword = string(dummyPasswordHash)
+	} else if err != nil {
+		return api.Session{}, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(storedPassword), []byte(password)); err != nil || p.ID == 0 {
+		log.Printf("Failed login attempt for '%s' from %s", name, client)
+		if err := s.limiter.Fail(keys...); err != nil {
+			log.Printf("Failed to record login attempt for '%s': %v", name, err)
+		}
+		return api.Session{}, errInvalidCredentials
+	}
+	// Счётчик клиента успешный вход не сбрасывает: иначе, входя в свой
+	// аккаунт между попытками, можно подбирать чужой пароль без блокировки.
+	// Он обнуляется сам, когда неудачи перестают повторяться
+	if err := s.limiter.Succeed(userKey); err != nil {
+		log.Printf("Failed to reset login attempts for '%s': %v", name, err)
+	}
+	log.Printf("Successfully authenticated player '%s' with ID %d", p.Name, p.ID)
+	return s.newSession(p, remember)
+}
+
+// Authorize возвращает игрока по токену и продлевает сессию.
+func (s *Service) Authorize(token string) (store.Player, error) {
+	if token == "" {
+		return store.Player{}, errUnauthorized
+	}
+	tokenHash := hashToken(token)
+	sess, err := s.store.SessionByToken(tokenHash, s.now())
+	if errors.Is(err, store.ErrNotFound) {
+		return store.Player{}, errUnauthorized
+	}
+	if err != nil {
+		return store.Player{}, err
+	}
+	p, err := s.store.PlayerByID(sess.PlayerID)
+	if errors.Is(err, store.ErrNotFound) {
+		return store.Player{}, errUnauthorized
+	}
+	if err != nil {
+		return store.Player{}, err
+	}
+	ttl := sessionTTL
+	if sess.Persistent {
+		ttl = persistentSessionTTL
+	}
+	if err := s.store.ExtendSession(tokenHash, s.now().Add(ttl)); err != nil {
+		log.Printf("Failed to extend session for player ID %d: %v", p.ID, err)
+	}
+	return p, nil
+}
+
+func (s *Service) Profile(token string) (api.Player, error) {
+	p, err := s.Authorize(token)
+	if err != nil {
+		return api.Player{}, err
+	}
+	return toAPIPlayer(p), nil
+}
+
+func (s *Service) Logout(token string) error {
+	if _, err := s.Authorize(token); err != nil {
+		return err
+	}
+	return s.store.DeleteSession(hashToken(token))
+}
+
+func (s *Service) LogoutAll(token string) error {
+	p, err := s.Authorize(token)
+	if err != nil {
+		return err
+	}
+	log.Printf("Revoking all sessions for player ID %d", p.ID)
+	return s.store.DeleteSessions(p.ID, "")
+}
+
+func (s *Service) ChangePassword(token, oldPassword, newPassword string) error {
+	p, err := s.Authorize(token)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(p.PasswordHash), []byte(oldPassword)); err != nil {
+		return errIncorrectPassword
+	}
+	if oldPassword == newPassword {
+		return badRequest("new password must differ from the old one")
+	}
+	if err := validatePassword(p.Name, newPassword); err != nil {
+		return err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %v", err)
+	}
+	if err := s.store.SetPassword(p.ID, string(hashedPassword)); err != nil {
+		return err
+	}
+	log.Printf("Password changed for player '%s' with ID %d", p.Name, p.ID)
+	// Остальные устройства должны войти заново с новым паролем
+	return s.store.DeleteSessions(p.ID, hashToken(token))
+}
+
+func (s *Service) DeleteAccount(token, password string) error {
+	p, err := s.Authorize(token)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(p.PasswordHash), []byte(password)); err != nil {
+		return errIncorrectPassword
+	}
+	if err := s.store.DeletePlayer(p.ID); err != nil {
+		return err
+	}
+	log.Printf("Deleted account '%s' with ID %d", p.Name, p.ID)
+	return nil
+}
+
+func (s *Service) SubmitGame(token string, result api.GameResult) (api.Player, error) {
+	p, err := s.Authorize(token)
+	if err != nil {
+		return api.Player{}, err
+	}
+	nonce, err := s.checkTickets(p, result)
+	if err != nil {
+		log.Printf("Rejected game from player '%s' with ID %d: %v", p.Name, p.ID, err)
+		return api.Player{}, err
+	}
+	tracker, err := s.achievementTracker(p, result.Slot)
+	if err != nil {
+		return api.Player{}, err
+	}
+	w, err := verifyReplay(result, tracker)
+	if err != nil {
+		log.Printf("Rejected game from player '%s' with ID %d: %v", p.Name, p.ID, err)
+		return api.Player{}, err
+	}
+	if err := s.checkStage(p, w.Stage); err != nil {
+		log.Printf("Rejected game from player '%s' with ID %d: %v", p.Name, p.ID, err)
+		return api.Player{}, err
+	}
+	if err := s.useTicket(p, nonce); err != nil {
+		return api.Player{}, err
+	}
+	// Призрак повторяет только одиночные забеги обычной партии на обычной
+	// сложности, поэтому партия на несколько игроков, этап кампании или
+	// другая сложность лучшим забегом не становятся
+	bestRun := result.Replay
+	if len(w.Wolves) > 1 || w.Stage != 0 || w.Difficulty != sim.DifficultyNormal {
+		bestRun = nil
+	}
+	updated, err := s.store.AddGame(p.ID, store.Game{
+		Score:       result.Score,
+		Lives:       result.Lives,
+		BestCombo:   result.BestCombo,
+		EggsCaught:  w.Wolves[result.Slot].Caught,
+		Level:       w.Level,
+		ReachedBoss: w.InBossRoom,
+		BossWon:     w.GameWon,
+		Replay:      bestRun,
+	})
+	if err != nil {
+		return api.Player{}, err
+	}
+	if w.Stage != 0 && w.GameWon {
+		if err := s.store.CompleteStage(p.ID, w.Stage); err != nil {
+			return api.Player{}, err
+		}
+		log.Printf("Player '%s' with ID %d completed campaign stage %d", p.Name, p.ID, w.Stage)
+	}
+	if err := s.unlockAchievements(p, tracker); err != nil {
+		return api.Player{}, err
+	}
+	return toAPIPlayer(updated), nil
+}
+
+func (s *Service) BestRun(token string) (api.BestRun, error) {
+	p, err := s.Authorize(token)
+	if err != nil {
+		return api.BestRun{}, err
+	}
+	replay, err := s.store.BestRun(p.ID)
+	if errors.Is(err, store.ErrNotFound) {
+		return api.BestRun{}, &api.Error{Status: http.StatusNotFound, Message: "no best run recorded yet"}
+	}
+	if err != nil {
+		return api.BestRun{}, err
+	}
+	return api.BestRun{Score: p.HighScore, Replay: replay}, nil
+}
+
+// verifyReplay проигрывает запись партии по тем же правилам, что и игра,
+// и принимает результат, только если счёт и жизни игрока result.Slot
+// совпали. Кадры записи проходят через tracker, если он не nil.
+func verifyReplay(result api.GameResult, tracker *gameTracker) (*sim.World, error) {
+	rec, err := sim.DecodeRecording(result.Replay)
+	if err != nil {
+		return nil, badRequest("invalid replay: %v", err)
+	}
+	if result.Slot < 0 || result.Slot >= rec.Players {
+		return nil, badRequest("invalid player slot %d", result.Slot)
+	}
+	var bus *sim.Bus
+	if tracker != nil {
+		bus = &sim.Bus{}
+		bus.SubscribeAll(tracker.handle)
+	}
+	w, err := sim.ReplayWith(rec, bus)
+	if err != nil {
+		return nil, &api.Error{Status: http.StatusUnprocessableEntity, Message: err.Error()}
+	}
+	if !w.GameOver && !w.GameWon {
+		return nil, &api.Error{Status: http.StatusUnprocessableEntity, Message: "replay does not finish the game"}
+	}
+	wolf := w.Wolves[result.Slot]
+	if wolf.Score != result.Score || wolf.Lives != result.Lives || wolf.BestCombo != result.BestCombo {
+		return nil, &api.Error{Status: http.StatusUnprocessableEntity, Message: "score does not match replay"}
+	}
+	return w, nil
+}
+
+func (s *Service) Stats(token string) (api.PlayerStats, error) {
+	p, err := s.Authorize(token)
+	if err != nil {
+		return api.PlayerStats{}, err
+	}
+	st, err := s.store.Stats(p.ID, recentGames)
+	if err != nil {
+		return api.PlayerStats{}, err
+	}
+	return api.PlayerStats{
+		Games:        st.Games,
+		AverageScore: st.AverageScore,
+		MedianScore:  st.MedianScore,
+		EggsCaught:   st.EggsCaught,
+		BestLevel:    st.BestLevel,
+		BossFights:   st.BossFights,
+		BossWins:     st.BossWins,
+		RecentScores: st.RecentScores,
+	}, nil
+}
+
+func (s *Service) Leaderboard(limit int) ([]api.Player, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 5
+	}
+	players, err := s.store.Leaderboard(limit)
+	if err != nil {
+		return nil, err
+	}
+	leaderboard := make([]api.Player, 0, len(players))
+	for _, p := range players {
+		leaderboard = append(leaderboard, toAPIPlayer(p))
+	}
+	return leaderboard, nil
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"egg_catcher2/api"
+	"egg_catcher2/sim"
+	"egg_catcher2/store"
+)
+
+// ticketTTL — сколько хранится билет: сохранённую партию можно
+// продолжить и через несколько дней.
+const ticketTTL = 30 * 24 * time.Hour
+
+var errGameSubmitted = &api.Error{Status: http.StatusConflict, Message: "game already submitted"}
+
+func randomInt64() (int64, error) {
+	var buf [8]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return 0, fmt.Errorf("failed to generate game ticket: %v", err)
+	}
+	return int64(binary.LittleEndian.Uint64(buf[:])), nil
+}
+
+// StartGame выдаёт билет на новую партию. Seed выбирает сервер, а с ghost
+// билет выдаётся на seed лучшего забега игрока.
+func (s *Service) StartGame(token string, ghost bool) (api.GameTicket, error) {
+	p, err := s.Authorize(token)
+	if err != nil {
+		return api.GameTicket{}, err
+	}
+	seed, err := randomInt64()
+	if err != nil {
+		return api.GameTicket{}, err
+	}
+	if ghost {
+		replay, err := s.store.BestRun(p.ID)
+		if errors.Is(err, store.ErrNotFound) {
+			return api.GameTicket{}, &api.Error{Status: http.StatusNotFound, Message: "no best run recorded yet"}
+		}
+		if err != nil {
+			return api.GameTicket{}, err
+		}
+		rec, err := sim.DecodeRecording(replay)
+		if err != nil {
+			return api.GameTicket{}, fmt.Errorf("failed to decode best run: %v", err)
+		}
+		seed = rec.Seed
+	}
+	nonce, err := randomInt64()
+	if err != nil {
+		return api.GameTicket{}, err
+	}
+	now := s.now()
+	if err := s.store.DeleteTickets(now.Add(-ticketTTL)); err != nil {
+		return api.GameTicket{}, err
+	}
+	err = s.store.CreateTicket(store.Ticket{Nonce: nonce, PlayerID: p.ID, Seed: seed, IssuedAt: now})
+	if err != nil {
+		return api.GameTicket{}, err
+	}
+	return api.GameTicket{Nonce: nonce, Seed: seed}, nil
+}
+
+// checkTickets сверяет запись партии с билетами её игроков и возвращает
+// билет игрока p. Seed записи должен сложиться из билетов, у каждого
+// игрока свой билет, а билет под номером result.Slot выдан игроку p и ещё
+// не использован: так нельзя подобрать seed, отправить партию повторно
+// или засчитать себе чужой номер.
+func (s *Service) checkTickets(p store.Player, result api.GameResult) (int64, error) {
+	rec, err := sim.DecodeRecording(result.Replay)
+	if err != nil {
+		return 0, badRequest("invalid replay: %v", err)
+	}
+	if len(result.Tickets) != rec.Players {
+		return 0, badRequest("expected %d game tickets, got %d", rec.Players, len(result.Tickets))
+	}
+	if result.Slot < 0 || result.Slot >= rec.Players {
+		return 0, badRequest("invalid player slot %d", result.Slot)
+	}
+	seeds := make([]int64, len(result.Tickets))
+	owners := make(map[int]bool)
+	for i, nonce := range result.Tickets {
+		t, err := s.store.Ticket(nonce)
+		if errors.Is(err, store.ErrNotFound) {
+			return 0, &api.Error{Status: http.StatusUnprocessableEntity, Message: "unknown game ticket"}
+		}
+		if err != nil {
+			return 0, err
+		}
+		if owners[t.PlayerID] {
+			return 0, &api.Error{Status: http.StatusUnprocessableEntity, Message: "each player needs their own game ticket"}
+		}
+		owners[t.PlayerID] = true
+		if i == result.Slot {
+			if t.PlayerID != p.ID {
+				return 0, &api.Error{Status: http.StatusForbidden, Message: "player slot belongs to another player"}
+			}
+			if t.Used {
+				return 0, errGameSubmitted
+			}
+		}
+		seeds[i] = t.Seed
+	}
+	if sim.CombineSeeds(seeds...) != rec.Seed {
+		return 0, &api.Error{Status: http.StatusUnprocessableEntity, Message: "replay seed does not match game tickets"}
+	}
+	return result.Tickets[result.Slot], nil
+}
+
+// useTicket тратит билет принятой партии. Проверка в checkTickets не
+// спасает от двух одновременных отправок, поэтому повтор ловится и здесь.
+func (s *Service) useTicket(p store.Player, nonce int64) error {
+	err := s.store.UseTicket(nonce)
+	if errors.Is(err, store.ErrTicketUsed) {
+		log.Printf("Rejected game from player '%s' with ID %d: %v", p.Name, p.ID, err)
+		return errGameSubmitted
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"egg_catcher2/api"
+)
+
+// Токен текущей сессии на сервере. Сохраняется на диск только при "remember me"
+var currentSessionToken string
+
+func sessionFile() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "session"), nil
+}
+
+func saveLocalSession(token string) error {
+	path, err := sessionFile()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(token), 0o600); err != nil {
+		return fmt.Errorf("failed to save session: %v", err)
+	}
+	return nil
+}
+
+func loadLocalSession() string {
+	path, err := sessionFile()
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func removeLocalSession() {
+	path, err := sessionFile()
+	if err != nil {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Error removing saved session: %v", err)
+	}
+}
+
+// resumeSession пытается войти по сохранённому токену. Отклонённый
+// сервером токен удаляется, чтобы не проверять его при каждом запуске.
+func resumeSession(backend api.Backend) (int, bool) {
+	token := loadLocalSession()
+	if token == "" {
+		return 0, false
+	}
+	player, err := backend.Profile(token)
+	if err != nil {
+		log.Printf("Saved session not accepted: %v", err)
+		var apiErr *api.Error
+		if errors.As(err, &apiErr) && apiErr.Status == http.StatusUnauthorized {
+			removeLocalSession()
+		}
+		return 0, false
+	}
+	currentSessionToken = token
+	log.Printf("Resumed session for player '%s' with ID %d", player.Name, player.ID)
+	return player.ID, true
+}
+
+func logOut(backend api.Backend) {
+	if currentSessionToken != "" {
+		if err := backend.Logout(currentSessionToken); err != nil {
+			log.Printf("Error logging out: %v", err)
+		}
+		currentSessionToken = ""
+	}
+	removeLocalSession()
+}
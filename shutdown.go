@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// shutdownTimeout ограничивает ожидание сервера при выходе: недоступный
+// сервер не должен держать закрытое окно.
+const shutdownTimeout = 5 * time.Second
+
+// notifyShutdown направляет SIGINT и SIGTERM в игровой цикл, чтобы выход
+// по сигналу проходил тем же путём, что и закрытие окна.
+func (w *GameWrapper) notifyShutdown() {
+	w.signals = make(chan os.Signal, 1)
+	signal.Notify(w.signals, os.Interrupt, syscall.SIGTERM)
+}
+
+// closing сообщает, что игру просят закрыть: окном или сигналом.
+func (w *GameWrapper) closing() bool {
+	if ebiten.IsWindowBeingClosed() {
+		log.Printf("Window closed")
+		return true
+	}
+	select {
+	case sig := <-w.signals:
+		log.Printf("Received %v", sig)
+		return true
+	default:
+		return false
+	}
+}
+
+// shutdown вызывается после выхода из игрового цикла: отправляет
+// результат законченной партии или сохраняет незаконченную в слот и
+// освобождает звуки.
+func (w *GameWrapper) shutdown() {
+	signal.Stop(w.signals)
+	if g := w.game; g != nil {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			g.flush()
+		}()
+		select {
+		case <-done:
+		case <-time.After(shutdownTimeout):
+			log.Printf("Timed out saving the game after %v", shutdownTimeout)
+		}
+	}
+	assets.close()
+}
+
+// flush сохраняет то, что иначе потерялось бы при выходе.
+func (g *Game) flush() {
+	if g.online != nil && !g.Over() {
+		// Соединение закрывается, чтобы соперник сразу узнал о выходе
+		g.online.client.Close()
+		return
+	}
+	if !g.Over() {
+		if err := g.saveSlot(); err != nil {
+			log.Printf("Error saving game: %v", err)
+		}
+		return
+	}
+	if g.replay != nil || g.saved {
+		return
+	}
+	if err := saveGameData(g); err != nil {
+		log.Printf("Error saving game data: %v", err)
+	}
+}
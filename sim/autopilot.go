@@ -0,0 +1,21 @@
+package sim
+
+// Autopilot возвращает ввод, который ведёт волка slot под самое низкое
+// полезное яйцо. Им играют тесты и бенчмарки, которым нужна партия с
+// очками, а не волк, стоящий на месте.
+func (w *World) Autopilot(slot int) Input {
+	target, lowest := 0.0, -1.0
+	for _, egg := range w.Eggs {
+		if egg.Active && !egg.IsHarmful && egg.Y > lowest {
+			target, lowest = egg.X, egg.Y
+		}
+	}
+	center := w.Wolves[slot].X + WolfWidth/2
+	switch {
+	case lowest >= 0 && target < center-5:
+		return InputLeft
+	case lowest >= 0 && target > center+5:
+		return InputRight
+	}
+	return 0
+}
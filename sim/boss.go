@@ -0,0 +1,360 @@
+package sim
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+)
+
+// Боссы описаны в bosses/*.json. Описания встроены в пакет, чтобы игра
+// и сервер, проверяющий запись партии, играли с одними и теми же
+// боссами.
+
+//go:embed bosses/*.json
+var bossFiles embed.FS
+
+// Движения босса
+const (
+	MovePatrol   = "patrol"   // Вправо-влево с постоянной скоростью
+	MoveSine     = "sine"     // По синусоиде вокруг центра экрана
+	MoveDash     = "dash"     // Рывками к волку
+	MoveTeleport = "teleport" // Перескакивает в случайное место
+)
+
+// Узоры яиц босса
+const (
+	PatternStraight = "straight" // Одно яйцо прямо вниз
+	PatternSpread   = "spread"   // Веер: полезное яйцо в середине, подделки по краям
+	PatternAimed    = "aimed"    // Одно яйцо в корзину ближайшего волка
+)
+
+const bossHitTime = 0.5 // Длительность анимации урона, с
+
+// BossPhase — поведение босса, пока его здоровье не больше Health.
+type BossPhase struct {
+	Health    int     `json:"health"`
+	Move      string  `json:"move"`
+	Speed     float64 `json:"speed"`     // Скорость движения за кадр
+	Amplitude float64 `json:"amplitude"` // Размах синусоиды
+	Period    float64 `json:"period"`    // Период синусоиды, с
+	Interval  float64 `json:"interval"`  // Пауза между рывками или прыжками, с
+	SpawnTime float64 `json:"spawn_time"`
+	Pattern   string  `json:"pattern"`
+	Count     int     `json:"count"`  // Яиц в веере
+	Spread    float64 `json:"spread"` // Разница VX соседних яиц веера
+	EggSpeed  float64 `json:"egg_speed"`
+}
+
+// BossType — описание босса. Sheet, Animation, Color и Tint нужны только
+// клиенту: лист кадров, анимация, цвет квадрата без картинки и оттенок
+// кадров.
+type BossType struct {
+	ID        string      `json:"id"`
+	Name      string      `json:"name"`
+	Health    int         `json:"health"`
+	Size      float64     `json:"size"` // Ширина босса на экране
+	Y         float64     `json:"y"`
+	Sheet     string      `json:"sheet"`
+	Animation string      `json:"animation"`
+	Color     [3]uint8    `json:"color"`
+	Tint      [3]uint8    `json:"tint,omitempty"` // Нулевой — кадры без оттенка
+	Phases    []BossPhase `json:"phases"`         // По убыванию Health, первая — с полным здоровьем
+}
+
+// BossTypes — все боссы в порядке имён файлов.
+var BossTypes = mustLoadBossTypes()
+
+func mustLoadBossTypes() []*BossType {
+	entries, err := bossFiles.ReadDir("bosses")
+	if err != nil {
+		panic(err)
+	}
+	var types []*BossType
+	for _, e := range entries {
+		data, err := bossFiles.ReadFile(path.Join("bosses", e.Name()))
+		if err != nil {
+			panic(err)
+		}
+		t := &BossType{}
+		if err := json.Unmarshal(data, t); err != nil {
+			panic(fmt.Sprintf("sim: failed to parse boss %s: %v", e.Name(), err))
+		}
+		if err := t.validate(); err != nil {
+			panic(fmt.Sprintf("sim: invalid boss %s: %v", e.Name(), err))
+		}
+		types = append(types, t)
+	}
+	if len(types) == 0 {
+		panic("sim: no bosses")
+	}
+	return types
+}
+
+func (t *BossType) validate() error {
+	if t.ID == "" || t.Health <= 0 || t.Size <= 0 || t.Size >= ScreenWidth/2 {
+		return fmt.Errorf("id, health or size is not set")
+	}
+	if len(t.Phases) == 0 || t.Phases[0].Health != t.Health {
+		return fmt.Errorf("first phase must start at full health")
+	}
+	for i, p := range t.Phases {
+		if i > 0 && p.Health >= t.Phases[i-1].Health {
+			return fmt.Errorf("phase %d: health must decrease", i)
+		}
+		if p.SpawnTime <= 0 || p.EggSpeed <= 0 {
+			return fmt.Errorf("phase %d: spawn time and egg speed must be positive", i)
+		}
+		switch p.Move {
+		case MovePatrol, MoveDash:
+			if p.Speed <= 0 || (p.Move == MoveDash && p.Interval <= 0) {
+				return fmt.Errorf("phase %d: %s needs speed and interval", i, p.Move)
+			}
+		case MoveSine:
+			if p.Speed <= 0 || p.Period <= 0 {
+				return fmt.Errorf("phase %d: sine needs speed and period", i)
+			}
+		case MoveTeleport:
+			if p.Interval <= 0 {
+				return fmt.Errorf("phase %d: teleport needs interval", i)
+			}
+		default:
+			return fmt.Errorf("phase %d: unknown move %q", i, p.Move)
+		}
+		switch p.Pattern {
+		case PatternStraight, PatternAimed:
+		case PatternSpread:
+			if p.Count < 2 {
+				return fmt.Errorf("phase %d: spread needs at least 2 eggs", i)
+			}
+		default:
+			return fmt.Errorf("phase %d: unknown pattern %q", i, p.Pattern)
+		}
+	}
+	return nil
+}
+
+// BossTypeByID ищет описание босса по ID.
+func BossTypeByID(id string) (*BossType, bool) {
+	for _, t := range BossTypes {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+type Boss struct {
+	Type              string  // BossType.ID
+	X, Y              float64 // Позиция центра босса
+	Speed             float64 // Скорость движения
+	Health            int
+	MaxHealth         int
+	Phase             int     // Номер фазы в BossType.Phases
+	Tick              int     // Кадров с начала фазы
+	TargetX           float64 // Куда направлен рывок
+	DodgeCount        int     // Счётчик уворотов
+	EggSpawnTime      float64 // Таймер спавна яиц
+	VX, VY            float64 // Скорость яиц
+	Direction         float64 // Направление (1 или -1)
+	HitAnimationTimer float64 // Таймер анимации урона
+	HitAnimationType  string  // "blink" или "explosion"
+}
+
+// Kind возвращает описание босса; для неизвестного ID, например из
+// снимка сервера другой версии, — первого босса.
+func (b *Boss) Kind() *BossType {
+	if t, ok := BossTypeByID(b.Type); ok {
+		return t
+	}
+	return BossTypes[0]
+}
+
+func (b *Boss) phase() *BossPhase {
+	return &b.Kind().Phases[b.Phase]
+}
+
+// bounds — пределы, в которых движется центр босса.
+func (b *Boss) bounds() (lo, hi float64) {
+	size := b.Kind().Size
+	return size, ScreenWidth - size
+}
+
+// moveTowards сдвигает босса к x не больше чем на speed.
+func (b *Boss) moveTowards(x, speed float64) {
+	b.X += math.Max(-speed, math.Min(speed, x-b.X))
+}
+
+// enterBossRoom выбирает босса и начинает его первую фазу. Босса этапа
+// кампании задаёт этап, в обычной партии он случайный.
+func (w *World) enterBossRoom(ev *Events) {
+	var t *BossType
+	if s := w.CurrentStage(); s != nil {
+		t, _ = BossTypeByID(s.Boss)
+	} else {
+		t = BossTypes[w.rng.Intn(len(BossTypes))]
+	}
+	p := t.Phases[0]
+	w.InBossRoom = true
+	w.Boss = &Boss{
+		Type:             t.ID,
+		X:                ScreenWidth / 2,
+		Y:                t.Y,
+		Speed:            p.Speed,
+		Health:           t.Health,
+		MaxHealth:        t.Health,
+		EggSpawnTime:     p.SpawnTime,
+		VY:               p.EggSpeed,
+		Direction:        1.0,
+		HitAnimationType: "blink",
+	}
+	ev.emit(Event{Type: EventBossEntered, Slot: -1})
+}
+
+// targetX — центр корзины волка, ближайшего к боссу, или центр экрана.
+func (w *World) targetX() float64 {
+	slot := w.nearestWolf(w.Boss.X)
+	if slot < 0 {
+		return ScreenWidth / 2
+	}
+	return w.Wolves[slot].X + WolfWidth/2
+}
+
+// moveBoss двигает босса по правилам текущей фазы.
+func (w *World) moveBoss() {
+	b := w.Boss
+	p := b.phase()
+	lo, hi := b.bounds()
+	interval := max(int(p.Interval*TicksPerSecond), 1)
+	switch p.Move {
+	case MovePatrol:
+		b.X += float64(p.Speed * b.Direction)
+		if b.X > hi || b.X < lo {
+			b.Direction *= -1
+		}
+	case MoveSine:
+		// Босс догоняет точку на синусоиде, поэтому в начале фазы не
+		// прыгает, а подлетает к ней
+		x := ScreenWidth/2 + float64(p.Amplitude*wave(float64(b.Tick)/float64(p.Period*TicksPerSecond)))
+		b.moveTowards(math.Max(lo, math.Min(hi, x)), p.Speed)
+	case MoveDash:
+		if b.Tick%interval == 0 {
+			b.TargetX = math.Max(lo, math.Min(hi, w.targetX()))
+		}
+		b.moveTowards(b.TargetX, p.Speed)
+	case MoveTeleport:
+		if b.Tick > 0 && b.Tick%interval == 0 {
+			b.X = lo + float64(w.rng.Float64()*(hi-lo))
+		}
+	}
+	b.Tick++
+}
+
+// spawnBossEggs сбрасывает яйца узором текущей фазы.
+func (w *World) spawnBossEggs(ev *Events) {
+	b := w.Boss
+	p := b.phase()
+	y := b.Y + b.Kind().Size/2
+	switch p.Pattern {
+	case PatternStraight:
+		w.layEgg(b.X, y, 0, p.EggSpeed, false, ev)
+	case PatternSpread:
+		middle := (p.Count - 1) / 2
+		for i := 0; i < p.Count; i++ {
+			vx := float64(float64(i)-float64(p.Count-1)/2) * p.Spread
+			w.layEgg(b.X, y, vx, p.EggSpeed, i != middle, ev)
+		}
+	case PatternAimed:
+		// Яйцо долетает до корзины за столько кадров, сколько падает
+		// по вертикали
+		slot := w.nearestWolf(b.X)
+		vx := 0.0
+		if slot >= 0 {
+			frames := (w.Wolves[slot].BasketY - y) / p.EggSpeed
+			vx = (w.targetX() - EggSize/2 - b.X) / math.Max(frames, 1)
+		}
+		w.layEgg(b.X, y, vx, p.EggSpeed, false, ev)
+	}
+}
+
+// hitBoss наносит боссу урон от яйца, пойманного волком slot. Когда
+// здоровье опускается до порога следующей фазы, босс переходит в неё,
+// а без здоровья партия заканчивается победой.
+func (w *World) hitBoss(damage, slot int, ev *Events) {
+	b := w.Boss
+	t := b.Kind()
+	b.Health = max(b.Health-damage, 0)
+	b.HitAnimationTimer = bossHitTime
+	b.HitAnimationType = "blink"
+	for b.Phase+1 < len(t.Phases) && b.Health <= t.Phases[b.Phase+1].Health {
+		b.Phase++
+		b.Tick = 0
+		p := b.phase()
+		b.Speed = p.Speed
+		b.VY = p.EggSpeed
+		b.EggSpawnTime = math.Min(b.EggSpawnTime, p.SpawnTime)
+		b.HitAnimationType = "explosion"
+	}
+	if b.Health == 0 {
+		b.HitAnimationType = "explosion"
+	}
+	ev.emit(Event{Type: EventBossHit, Slot: slot, X: b.X, Y: b.Y})
+	if b.Health == 0 {
+		w.GameWon = true
+		ev.emit(Event{Type: EventGameOver, Slot: -1, Won: true, Difficulty: w.Difficulty})
+	}
+}
+
+func (w *World) stepBossRoom(inputs []Input, ev *Events) {
+	b := w.Boss
+	w.moveBoss()
+	b.HitAnimationTimer = math.Max(b.HitAnimationTimer-1.0/TicksPerSecond, 0)
+
+	// Спавн яиц
+	b.EggSpawnTime -= 1.0 / TicksPerSecond
+	if b.EggSpawnTime <= 0 {
+		w.spawnBossEggs(ev)
+		b.EggSpawnTime = b.phase().SpawnTime
+	}
+
+	// Обработка яиц (движение, ловля, жизни). В замедлении яйца
+	// двигаются через кадр, от стен отскакивают
+	if !w.slowMotion() || w.Frame%2 == 0 {
+		for i := range w.Eggs {
+			egg := &w.Eggs[i]
+			if !egg.Active {
+				continue
+			}
+			egg.X += egg.VX
+			if egg.X < 0 || egg.X > ScreenWidth-EggSize {
+				egg.VX = -egg.VX
+			}
+			egg.Y += egg.VY
+			w.applyMagnet(egg)
+			w.catchOrMiss(egg, ev)
+			if w.GameWon {
+				break
+			}
+		}
+		w.removeInactiveEggs()
+	}
+
+	// Движение волков
+	w.moveWolves(inputs)
+}
+
+// wave — синусоида с периодом 1 по приближению Бхаскары: math.Sin
+// устроен по-разному на разных процессорах, а запись партии должна
+// воспроизводиться везде одинаково.
+func wave(p float64) float64 {
+	p -= math.Floor(p)
+	sign := 1.0
+	if p >= 0.5 {
+		p -= 0.5
+		sign = -1
+	}
+	u := float64(2 * p)
+	k := float64(u * (1 - u))
+	return sign * float64(16*k) / (5 - float64(4*k))
+}
@@ -0,0 +1,29 @@
+package sim
+
+import "testing"
+
+// Яйца, пойманные в комнате босса, бьют по нему: попадание публикует
+// EventBossHit, смена фазы и победа показываются взрывом.
+func TestCaughtEggsHitBoss(t *testing.T) {
+	var hits, explosions int
+	for seed := int64(1); seed <= 20 && explosions == 0; seed++ {
+		w := NewWorld(seed)
+		for !w.Over() {
+			for _, e := range w.Step(w.Autopilot(0)) {
+				if e.Type != EventBossHit {
+					continue
+				}
+				if e.Slot != 0 {
+					t.Fatalf("boss hit by slot %d", e.Slot)
+				}
+				hits++
+				if w.Boss.HitAnimationType == "explosion" {
+					explosions++
+				}
+			}
+		}
+	}
+	if hits == 0 || explosions == 0 {
+		t.Fatalf("%d boss hits, %d explosions", hits, explosions)
+	}
+}
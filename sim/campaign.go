@@ -0,0 +1,82 @@
+package sim
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+)
+
+// Stage — этап кампании: свои куры, начальный уровень и босс, который
+// появляется, когда игроки набирают BossScore очков. Background — имя
+// картинки фона для клиента.
+type Stage struct {
+	ID         string        `json:"id"`
+	Name       string        `json:"name"`
+	Background string        `json:"background"`
+	Level      int           `json:"level"`
+	BossScore  int           `json:"boss_score"`
+	Boss       string        `json:"boss"`
+	Hens       [4][2]float64 `json:"hens"`
+}
+
+//go:embed campaign.json
+var campaignData []byte
+
+// Stages — этапы кампании по порядку. Этапы нумеруются с единицы: этап n
+// — это Stages[n-1], а ноль в World.Stage и Recording.Stage означает
+// обычную партию.
+var Stages = mustLoadStages()
+
+func mustLoadStages() []Stage {
+	var stages []Stage
+	if err := json.Unmarshal(campaignData, &stages); err != nil {
+		panic(fmt.Sprintf("sim: failed to parse campaign: %v", err))
+	}
+	if len(stages) == 0 || len(stages) > 255 {
+		panic("sim: campaign must have 1 to 255 stages")
+	}
+	for _, s := range stages {
+		if _, ok := BossTypeByID(s.Boss); !ok {
+			panic(fmt.Sprintf("sim: stage %s: unknown boss %q", s.ID, s.Boss))
+		}
+		if s.Level < 1 || s.Level > MaxLevel || s.BossScore <= 0 {
+			panic(fmt.Sprintf("sim: stage %s: invalid level or boss score", s.ID))
+		}
+	}
+	return stages
+}
+
+// NewStageWorld создаёт партию этапа кампании stage (с единицы); stage 0
+// — обычная партия, как NewMultiplayerWorld.
+func NewStageWorld(seed int64, players, stage int) *World {
+	w := NewMultiplayerWorld(seed, players)
+	if stage == 0 {
+		return w
+	}
+	if stage < 0 || stage > len(Stages) {
+		panic("sim: invalid stage")
+	}
+	s := &Stages[stage-1]
+	w.Stage = stage
+	w.Level = s.Level
+	for i, h := range s.Hens {
+		w.Hens[i] = Hen{X: h[0], Y: h[1]}
+	}
+	return w
+}
+
+// CurrentStage возвращает этап кампании партии или nil для обычной.
+func (w *World) CurrentStage() *Stage {
+	if w.Stage == 0 {
+		return nil
+	}
+	return &Stages[w.Stage-1]
+}
+
+// bossScore — очки, при которых появляется босс.
+func (w *World) bossScore() int {
+	if s := w.CurrentStage(); s != nil {
+		return s.BossScore
+	}
+	return BossScoreThreshold
+}
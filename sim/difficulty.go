@@ -0,0 +1,30 @@
+package sim
+
+// Difficulty — сложность партии: от неё зависит скорость яиц. Сложность
+// хранится в записи партии, чтобы сервер проверял результат по тем же
+// правилам. Нулевое значение — обычная сложность, на ней идут и записи
+// старых версий.
+type Difficulty uint8
+
+const (
+	DifficultyNormal Difficulty = iota
+	DifficultyEasy
+	DifficultyHard
+	DifficultyCount
+)
+
+var difficultyNames = [DifficultyCount]string{"Normal", "Easy", "Hard"}
+
+// difficultySpeed — множитель скорости и ускорения яиц кур.
+var difficultySpeed = [DifficultyCount]float64{1, 0.8, 1.25}
+
+func (d Difficulty) String() string {
+	if d >= DifficultyCount {
+		return "Unknown"
+	}
+	return difficultyNames[d]
+}
+
+func (d Difficulty) speed() float64 {
+	return difficultySpeed[d]
+}
@@ -0,0 +1,22 @@
+package sim
+
+import "testing"
+
+// fallTime считает кадры, за которые первое яйцо партии падает на землю.
+func fallTime(d Difficulty) int {
+	w := NewWorld(1)
+	w.Difficulty = d
+	w.Wolves[0].X = 0 // Волк в углу не ловит яйца
+	for frame := 1; ; frame++ {
+		if w.Step().Has(EventEggMissed) {
+			return frame
+		}
+	}
+}
+
+func TestHarderDifficultyDropsEggsFaster(t *testing.T) {
+	easy, normal, hard := fallTime(DifficultyEasy), fallTime(DifficultyNormal), fallTime(DifficultyHard)
+	if !(easy > normal && normal > hard) {
+		t.Fatalf("fall time: easy %d, normal %d, hard %d frames", easy, normal, hard)
+	}
+}
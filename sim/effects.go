@@ -0,0 +1,118 @@
+package sim
+
+// Effect — временный эффект, который даёт пойманное яйцо-бонус.
+type Effect uint8
+
+const (
+	EffectNone         Effect = iota
+	EffectWideBasket          // Корзина шире в полтора раза
+	EffectSlowMotion          // Яйца падают вдвое медленнее
+	EffectMagnet              // Корзина притягивает полезные яйца поблизости
+	EffectShield              // Щит поглощает одно вредное яйцо
+	EffectDoublePoints        // Двойные очки
+	EffectCount
+)
+
+// EffectInfo описывает бонус: название для интерфейса, вероятность
+// выпадения среди всех яиц и длительность в кадрах.
+type EffectInfo struct {
+	Name     string
+	Chance   float64
+	Duration int
+}
+
+// Вероятности выпадения яиц. Остаток после подделок, белых яиц и
+// бонусов приходится на золотые яйца.
+const (
+	FakeEggChance  = 0.1
+	WhiteEggChance = 0.05
+)
+
+// Effects задаёт редкость и длительность бонусов. Сумма Chance вместе с
+// FakeEggChance и WhiteEggChance должна быть меньше 1.
+var Effects = [EffectCount]EffectInfo{
+	EffectWideBasket:   {Name: "Wide Basket", Chance: 0.025, Duration: 10 * TicksPerSecond},
+	EffectSlowMotion:   {Name: "Slow Motion", Chance: 0.02, Duration: 6 * TicksPerSecond},
+	EffectMagnet:       {Name: "Magnet", Chance: 0.02, Duration: 8 * TicksPerSecond},
+	EffectShield:       {Name: "Shield", Chance: 0.02, Duration: 15 * TicksPerSecond},
+	EffectDoublePoints: {Name: "Double Points", Chance: 0.015, Duration: 10 * TicksPerSecond},
+}
+
+const (
+	WideBasketScale = 1.5
+	MagnetRadius    = 120.0 // Радиус притяжения вокруг центра корзины
+	MagnetPull      = 2.0   // Сдвиг яйца к корзине за кадр
+)
+
+// PowerUpEggValue — Egg.Value яйца-бонуса; какой это бонус, хранит
+// Egg.Effect.
+const PowerUpEggValue = 3
+
+// rollEgg выбирает вид нового яйца по одному случайному числу.
+func rollEgg(p float64) (value int, isHarmful bool, effect Effect) {
+	if p < FakeEggChance {
+		return 0, true, EffectNone // fake_egg
+	}
+	p -= FakeEggChance
+	if p < WhiteEggChance {
+		return 1, false, EffectNone // white_egg
+	}
+	p -= WhiteEggChance
+	for e := EffectNone + 1; e < EffectCount; e++ {
+		if p < Effects[e].Chance {
+			return PowerUpEggValue, false, e
+		}
+		p -= Effects[e].Chance
+	}
+	return 2, false, EffectNone // gold_egg
+}
+
+// Active сообщает, действует ли эффект на волка.
+func (wf *Wolf) Active(e Effect) bool {
+	return wf.Effects[e] > 0
+}
+
+func (wf *Wolf) basketWidth() float64 {
+	if wf.Active(EffectWideBasket) {
+		return BasketWidth * WideBasketScale
+	}
+	return BasketWidth
+}
+
+// tickEffects отсчитывает оставшееся время эффектов.
+func (wf *Wolf) tickEffects() {
+	for e := range wf.Effects {
+		if wf.Effects[e] > 0 {
+			wf.Effects[e]--
+		}
+	}
+}
+
+// slowMotion сообщает, замедлено ли время хотя бы одним из игроков.
+func (w *World) slowMotion() bool {
+	for i := range w.Wolves {
+		if w.Wolves[i].Active(EffectSlowMotion) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyMagnet подтягивает падающие полезные яйца к корзинам волков с
+// магнитом.
+func (w *World) applyMagnet(egg *Egg) {
+	if egg.IsHarmful || egg.Phase != "falling" {
+		return
+	}
+	for i := range w.Wolves {
+		wf := &w.Wolves[i]
+		if wf.Out() || !wf.Active(EffectMagnet) {
+			continue
+		}
+		dx := wf.X + WolfWidth/2 - egg.X
+		if dx > -MagnetRadius && dx < MagnetRadius && egg.Y > wf.BasketY-MagnetRadius {
+			egg.X += max(-MagnetPull, min(MagnetPull, dx))
+			return
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package sim
+
+// EventType — вид события шага симуляции.
+type EventType uint8
+
+const (
+	EventEggCaught   EventType = iota // Игрок поймал полезное яйцо или бонус
+	EventEggMissed                    // Полезное яйцо упало на землю
+	EventLifeLost                     // Промах или пойманная подделка
+	EventLifeGained                   // Белое яйцо вернуло жизнь
+	EventLevelUp                      // Яйца стали падать быстрее
+	EventBossEntered                  // Началась комната босса
+	EventBossHit                      // Босс получил урон
+	EventGameOver                     // Партия закончилась, Won — победой
+	EventShieldUsed                   // Щит поглотил подделку
+	EventComboBroken                  // Игрок прервал серию поимок
+	EventEggLaid                      // Курица Hen или босс (Hen -1) сбросили яйцо
+	EventTypeCount
+)
+
+// Event — одно событие шага. Поля, не относящиеся к виду события,
+// остаются нулевыми; у событий всей партии Slot равен -1.
+type Event struct {
+	Type   EventType
+	Slot   int // Игрок, к которому относится событие
+	X, Y   float64
+	Value  int    // Egg.Value пойманного или упущенного яйца
+	Effect Effect // Бонус пойманного яйца
+	Points int    // Очки за поимку
+	Combo  int    // Серия игрока после поимки
+	Level  int    // Новый уровень при EventLevelUp
+	Hen    int    // Номер курицы при EventEggLaid
+	Won    bool
+	// Difficulty — сложность партии при EventGameOver
+	Difficulty Difficulty
+}
+
+// Events — события одного шага в порядке, в котором они произошли.
+type Events []Event
+
+func (ev *Events) emit(e Event) {
+	*ev = append(*ev, e)
+}
+
+// Has сообщает, было ли за шаг событие вида t.
+func (ev Events) Has(t EventType) bool {
+	for _, e := range ev {
+		if e.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Bus раздаёт события шага подписчикам: звуку, эффектам, статистике и
+// достижениям. Подписчики вызываются в порядке подписки.
+type Bus struct {
+	handlers [EventTypeCount][]func(Event)
+}
+
+// Subscribe вызывает h для каждого события вида t.
+func (b *Bus) Subscribe(t EventType, h func(Event)) {
+	b.handlers[t] = append(b.handlers[t], h)
+}
+
+// SubscribeAll вызывает h для событий всех видов.
+func (b *Bus) SubscribeAll(h func(Event)) {
+	for t := range b.handlers {
+		b.handlers[t] = append(b.handlers[t], h)
+	}
+}
+
+func (b *Bus) Publish(events Events) {
+	for _, e := range events {
+		for _, h := range b.handlers[e.Type] {
+			h(e)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package sim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Input — состояние управления в одном кадре.
+type Input uint8
+
+const (
+	InputLeft Input = 1 << iota
+	InputRight
+)
+
+const (
+	recordingMagic = "EGGR"
+	// Версия 1 хранит ввод одного игрока, версия 2 — число игроков и ввод
+	// всех игроков кадра, упакованный в один байт, версия 3 — ещё и этап
+	// кампании, версия 4 — сложность.
+	recordingVersion = 4
+	// MaxRecordingFrames ограничивает длину записи двумя часами игры.
+	MaxRecordingFrames = 2 * 60 * 60 * TicksPerSecond
+)
+
+// Recording — seed и ввод по кадрам, из которых партия восстанавливается
+// однозначно. Inputs хранит кадры подряд: ввод игрока p в кадре i лежит
+// в Inputs[i*Players+p].
+type Recording struct {
+	Seed       int64
+	Players    int
+	Stage      int // Этап кампании, 0 — обычная партия
+	Difficulty Difficulty
+	Inputs     []Input
+}
+
+func NewRecording(seed int64) *Recording {
+	return NewMultiplayerRecording(seed, 1)
+}
+
+func NewMultiplayerRecording(seed int64, players int) *Recording {
+	return &Recording{Seed: seed, Players: players}
+}
+
+// CombineSeeds выводит seed партии на нескольких игроков из seed'ов их
+// билетов по номерам игроков. Seed одиночной партии остаётся как есть.
+func CombineSeeds(seeds ...int64) int64 {
+	if len(seeds) == 1 {
+		return seeds[0]
+	}
+	// Перемешивание splitmix64
+	var h uint64
+	for _, s := range seeds {
+		h ^= uint64(s)
+		h += 0x9e3779b97f4a7c15
+		h = (h ^ h>>30) * 0xbf58476d1ce4e5b9
+		h = (h ^ h>>27) * 0x94d049bb133111eb
+		h ^= h >> 31
+	}
+	return int64(h)
+}
+
+// Add добавляет кадр; inputs[i] — ввод игрока i.
+func (r *Recording) Add(inputs ...Input) {
+	for p := 0; p < r.Players; p++ {
+		var in Input
+		if p < len(inputs) {
+			in = inputs[p]
+		}
+		r.Inputs = append(r.Inputs, in)
+	}
+}
+
+// Frames возвращает число записанных кадров.
+func (r *Recording) Frames() int {
+	return len(r.Inputs) / r.Players
+}
+
+// Frame возвращает ввод всех игроков в кадре i.
+func (r *Recording) Frame(i int) []Input {
+	return r.Inputs[i*r.Players : (i+1)*r.Players]
+}
+
+// packFrame укладывает ввод игроков кадра в байт, по два бита на игрока.
+func (r *Recording) packFrame(i int) byte {
+	var b byte
+	for p, in := range r.Frame(i) {
+		b |= byte(in&(InputLeft|InputRight)) << (2 * p)
+	}
+	return b
+}
+
+// Encode сжимает кадры в пары (значение, число повторов): волки подолгу
+// стоят или идут в одну сторону, поэтому запись получается короткой.
+func (r *Recording) Encode() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(recordingMagic)
+	buf.WriteByte(recordingVersion)
+	buf.Write(binary.AppendVarint(nil, r.Seed))
+	buf.WriteByte(byte(r.Players))
+	buf.WriteByte(byte(r.Stage))
+	buf.WriteByte(byte(r.Difficulty))
+	frames := r.Frames()
+	buf.Write(binary.AppendUvarint(nil, uint64(frames)))
+	for i := 0; i < frames; {
+		packed := r.packFrame(i)
+		j := i
+		for j < frames && r.packFrame(j) == packed {
+			j++
+		}
+		buf.WriteByte(packed)
+		buf.Write(binary.AppendUvarint(nil, uint64(j-i)))
+		i = j
+	}
+	return buf.Bytes()
+}
+
+func DecodeRecording(data []byte) (*Recording, error) {
+	if len(data) < len(recordingMagic)+1 || string(data[:len(recordingMagic)]) != recordingMagic {
+		return nil, errors.New("not a recording")
+	}
+	version := data[len(recordingMagic)]
+	if version < 1 || version > recordingVersion {
+		return nil, fmt.Errorf("unsupported recording version %d", version)
+	}
+	r := bytes.NewReader(data[len(recordingMagic)+1:])
+	seed, err := binary.ReadVarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed: %v", err)
+	}
+	players := 1
+	if version >= 2 {
+		n, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read player count: %v", err)
+		}
+		if n < 1 || n > MaxPlayers {
+			return nil, fmt.Errorf("invalid player count %d", n)
+		}
+		players = int(n)
+	}
+	stage := 0
+	if version >= 3 {
+		n, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stage: %v", err)
+		}
+		if int(n) > len(Stages) {
+			return nil, fmt.Errorf("invalid stage %d", n)
+		}
+		stage = int(n)
+	}
+	difficulty := DifficultyNormal
+	if version >= 4 {
+		n, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read difficulty: %v", err)
+		}
+		if Difficulty(n) >= DifficultyCount {
+			return nil, fmt.Errorf("invalid difficulty %d", n)
+		}
+		difficulty = Difficulty(n)
+	}
+	total, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read frame count: %v", err)
+	}
+	if total > MaxRecordingFrames {
+		return nil, fmt.Errorf("recording too long: %d frames", total)
+	}
+	rec := &Recording{Seed: seed, Players: players, Stage: stage, Difficulty: difficulty, Inputs: make([]Input, 0, total*uint64(players))}
+	for uint64(rec.Frames()) < total {
+		packed, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input: %v", err)
+		}
+		count, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input count: %v", err)
+		}
+		if count == 0 || count > total-uint64(rec.Frames()) {
+			return nil, fmt.Errorf("invalid input run of %d frames", count)
+		}
+		for ; count > 0; count-- {
+			for p := 0; p < players; p++ {
+				rec.Inputs = append(rec.Inputs, Input(packed>>(2*p))&(InputLeft|InputRight))
+			}
+		}
+	}
+	if r.Len() != 0 {
+		return nil, errors.New("trailing data after recording")
+	}
+	return rec, nil
+}
+
+// Replay заново проигрывает запись и возвращает итоговое состояние.
+// Запись с кадрами после окончания игры считается неверной.
+func Replay(rec *Recording) (*World, error) {
+	return ReplayWith(rec, nil)
+}
+
+// ReplayWith проигрывает запись как Replay и публикует события каждого
+// кадра в bus, если он не nil.
+func ReplayWith(rec *Recording, bus *Bus) (*World, error) {
+	w := NewStageWorld(rec.Seed, rec.Players, rec.Stage)
+	w.Difficulty = rec.Difficulty
+	for i := 0; i < rec.Frames(); i++ {
+		if w.GameOver || w.GameWon {
+			return nil, fmt.Errorf("recording continues %d frames after the game ended", rec.Frames()-i)
+		}
+		ev := w.Step(rec.Frame(i)...)
+		if bus != nil {
+			bus.Publish(ev)
+		}
+	}
+	return w, nil
+}
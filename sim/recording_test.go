@@ -0,0 +1,147 @@
+package sim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// record играет партию на автопилоте до конца и возвращает запись вместе
+// с итоговым миром.
+func record(t *testing.T, seed int64, players, stage int, d Difficulty) (*Recording, *World) {
+	t.Helper()
+	w := NewStageWorld(seed, players, stage)
+	w.Difficulty = d
+	rec := NewMultiplayerRecording(seed, players)
+	rec.Stage = stage
+	rec.Difficulty = d
+	inputs := make([]Input, players)
+	for !w.Over() {
+		if rec.Frames() == MaxRecordingFrames {
+			t.Fatal("game did not finish")
+		}
+		for i := range inputs {
+			inputs[i] = w.Autopilot(i)
+		}
+		// Второй игрок иногда стоит, чтобы кадры различались между игроками
+		if players > 1 && w.Frame%7 == 0 {
+			inputs[1] = 0
+		}
+		rec.Add(inputs...)
+		w.Step(inputs...)
+	}
+	return rec, w
+}
+
+func replayEncoded(t *testing.T, data []byte) (*Recording, *World) {
+	t.Helper()
+	rec, err := DecodeRecording(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := Replay(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec, w
+}
+
+func TestRecordingReplaysGame(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		players, stage int
+		difficulty     Difficulty
+	}{
+		{"single", 1, 0, DifficultyNormal},
+		{"two players", 2, 0, DifficultyNormal},
+		{"stage", 1, 2, DifficultyNormal},
+		{"hard", 1, 0, DifficultyHard},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec, want := record(t, 42, tc.players, tc.stage, tc.difficulty)
+			decoded, got := replayEncoded(t, rec.Encode())
+			if !reflect.DeepEqual(decoded, rec) {
+				t.Fatalf("decoded recording differs: seed %d players %d stage %d frames %d",
+					decoded.Seed, decoded.Players, decoded.Stage, decoded.Frames())
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("replayed world differs: score %d, want %d, frame %d, want %d",
+					got.TotalScore(), want.TotalScore(), got.Frame, want.Frame)
+			}
+		})
+	}
+}
+
+func TestReplayRejectsFramesAfterGameOver(t *testing.T) {
+	rec, _ := record(t, 7, 1, 0, DifficultyNormal)
+	rec.Add(InputLeft)
+	if _, err := Replay(rec); err == nil {
+		t.Fatal("expected an error for frames after the game ended")
+	}
+}
+
+// encodeVersion кодирует запись в формате старой версии: в версии 1 нет
+// числа игроков, в версии 2 — этапа кампании, в версии 3 — сложности.
+func encodeVersion(rec *Recording, version byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(recordingMagic)
+	buf.WriteByte(version)
+	buf.Write(binary.AppendVarint(nil, rec.Seed))
+	if version >= 2 {
+		buf.WriteByte(byte(rec.Players))
+	}
+	if version >= 3 {
+		buf.WriteByte(byte(rec.Stage))
+	}
+	frames := rec.Frames()
+	buf.Write(binary.AppendUvarint(nil, uint64(frames)))
+	for i := 0; i < frames; i++ {
+		buf.WriteByte(rec.packFrame(i))
+		buf.Write(binary.AppendUvarint(nil, 1))
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeOlderVersions(t *testing.T) {
+	for _, tc := range []struct {
+		version byte
+		players int
+		stage   int
+	}{
+		{1, 1, 0},
+		{2, 1, 0},
+		{2, 2, 0},
+		{3, 1, 2},
+	} {
+		rec, want := record(t, 5, tc.players, tc.stage, DifficultyNormal)
+		decoded, got := replayEncoded(t, encodeVersion(rec, tc.version))
+		if !reflect.DeepEqual(decoded, rec) {
+			t.Fatalf("version %d, %d players: decoded recording differs", tc.version, tc.players)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("version %d, %d players: replayed world differs", tc.version, tc.players)
+		}
+	}
+}
+
+func TestDecodeRejectsInvalidRecordings(t *testing.T) {
+	rec, _ := record(t, 3, 1, 0, DifficultyNormal)
+	data := rec.Encode()
+	future := bytes.Clone(data)
+	future[len(recordingMagic)] = recordingVersion + 1
+	rec.Difficulty = DifficultyCount
+	badDifficulty := rec.Encode()
+	for name, data := range map[string][]byte{
+		"empty":          nil,
+		"bad magic":      append([]byte("EGGX"), data[len(recordingMagic):]...),
+		"future version": future,
+		"bad difficulty": badDifficulty,
+		"truncated":      data[:len(data)-1],
+		"trailing data":  append(bytes.Clone(data), 0),
+	} {
+		if _, err := DecodeRecording(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,420 @@
+// Package sim содержит правила игры без графики и звука: движение волка,
+// яйца, уровни и комнату босса. Один и тот же код выполняется в игре и на
+// сервере при проверке записи партии, поэтому шаг симуляции должен быть
+// детерминированным: случайность берётся только из rng, а вместо живой
+// клавиатуры на вход подаётся Input.
+//
+// Умножения, результат которых сразу складывается, обёрнуты в float64(...):
+// явное преобразование запрещает компилятору объединять их в FMA, и запись
+// воспроизводится одинаково на amd64 и arm64.
+package sim
+
+import (
+	"math"
+	"math/rand"
+)
+
+const (
+	ScreenWidth        = 800
+	ScreenHeight       = 600
+	WolfWidth          = 50
+	WolfHeight         = 50
+	BasketWidth        = 80
+	BasketHeight       = 60
+	HenWidth           = 38
+	HenHeight          = 38
+	EggSize            = 14
+	MaxLives           = 3
+	MaxLevel           = 20
+	BossScoreThreshold = 5 // Очки для появления босса
+	TicksPerSecond     = 60
+	WhiteEggPoints     = 1
+	GoldEggPoints      = 2
+	ComboStep          = 5 // Каждые ComboStep поимок подряд увеличивают множитель
+	MaxMultiplier      = 5
+	MaxPlayers         = 4 // Ввод всех игроков кадра помещается в один байт записи
+)
+
+type Hen struct {
+	X, Y float64
+}
+
+type Egg struct {
+	ID          int // Номер яйца в партии, по нему клиент сетевой игры сопоставляет снимки
+	X, Y        float64
+	VX, VY      float64
+	Phase       string
+	TransitionX float64
+	Active      bool
+	Value       int
+	IsHarmful   bool   // Вредное (true) или полезное (false)
+	Effect      Effect // Эффект яйца-бонуса
+}
+
+// Wolf — волк одного игрока со своей корзиной, счётом и жизнями.
+type Wolf struct {
+	X, Y      float64
+	BasketY   float64
+	Score     int
+	Lives     int
+	IsMoving  bool
+	Effects   [EffectCount]int // Оставшиеся кадры действия эффектов
+	Combo     int              // Поимок подряд без промаха и подделки
+	BestCombo int
+	Caught    int // Поймано полезных яиц за партию
+}
+
+// Multiplier — множитель очков за текущую серию поимок.
+func (wf *Wolf) Multiplier() int {
+	return min(1+wf.Combo/ComboStep, MaxMultiplier)
+}
+
+func (wf *Wolf) breakCombo() {
+	wf.Combo = 0
+}
+
+// Out сообщает, что игрок потерял все жизни и выбыл из партии.
+func (wf *Wolf) Out() bool {
+	return wf.Lives <= 0
+}
+
+type World struct {
+	Wolves     []Wolf // Волк каждого игрока, по порядку ввода
+	Hens       [4]Hen
+	Eggs       []Egg
+	Level      int
+	Boss       *Boss // Указатель на босса
+	InBossRoom bool  // Флаг комнаты босса
+	GameOver   bool
+	GameWon    bool // Флаг победы
+	Frame      int  // Число выполненных шагов
+	Seed       int64
+	Stage      int        // Этап кампании, 0 — обычная партия
+	Difficulty Difficulty // Задаётся до первого шага
+	rng        *rand.Rand
+	eggCount   int // Сколько яиц появилось за партию
+}
+
+func NewWorld(seed int64) *World {
+	return NewMultiplayerWorld(seed, 1)
+}
+
+// NewMultiplayerWorld создаёт партию на несколько волков, которые делят
+// между собой четырёх кур. Волки расставляются по экрану равномерно.
+func NewMultiplayerWorld(seed int64, players int) *World {
+	if players < 1 || players > MaxPlayers {
+		panic("sim: invalid number of players")
+	}
+	w := &World{
+		Wolves: make([]Wolf, players),
+		Level:  1,
+		Seed:   seed,
+		rng:    rand.New(rand.NewSource(seed)),
+	}
+	for i := range w.Wolves {
+		w.Wolves[i] = Wolf{
+			X:       float64(ScreenWidth*(i+1)/(players+1) - WolfWidth/2),
+			Y:       ScreenHeight - WolfHeight - 20,
+			BasketY: 460,
+			Lives:   MaxLives,
+		}
+	}
+	w.Hens[0] = Hen{X: 150, Y: 58}
+	w.Hens[1] = Hen{X: 100, Y: 108}
+	w.Hens[2] = Hen{X: 650, Y: 58}
+	w.Hens[3] = Hen{X: 700, Y: 108}
+	return w
+}
+
+// TotalScore — сумма очков всех игроков; от неё зависят уровень и
+// появление босса.
+func (w *World) TotalScore() int {
+	total := 0
+	for _, wf := range w.Wolves {
+		total += wf.Score
+	}
+	return total
+}
+
+// Over сообщает, что партия закончилась поражением или победой.
+func (w *World) Over() bool {
+	return w.GameOver || w.GameWon
+}
+
+// activeWolves возвращает число игроков, которые ещё не выбыли.
+func (w *World) activeWolves() int {
+	n := 0
+	for i := range w.Wolves {
+		if !w.Wolves[i].Out() {
+			n++
+		}
+	}
+	return n
+}
+
+// nearestWolf возвращает номер волка, ближайшего к x среди оставшихся в
+// игре, или -1: он отвечает за яйцо, упавшее мимо корзин.
+func (w *World) nearestWolf(x float64) int {
+	nearest := -1
+	for i := range w.Wolves {
+		wf := &w.Wolves[i]
+		if wf.Out() {
+			continue
+		}
+		if nearest < 0 || math.Abs(wf.X+WolfWidth/2-x) < math.Abs(w.Wolves[nearest].X+WolfWidth/2-x) {
+			nearest = i
+		}
+	}
+	return nearest
+}
+
+// loseLife отнимает жизнь у волка slot и прерывает его серию.
+func (w *World) loseLife(slot int, ev *Events) {
+	wf := &w.Wolves[slot]
+	wf.Lives--
+	ev.emit(Event{Type: EventLifeLost, Slot: slot})
+	if wf.Combo > 0 {
+		ev.emit(Event{Type: EventComboBroken, Slot: slot, Combo: wf.Combo})
+	}
+	wf.breakCombo()
+}
+
+func (w *World) spawnEgg(ev *Events) {
+	valueEgg, isHarmful, effect := rollEgg(w.rng.Float64())
+	henIndex := w.rng.Intn(4)
+	eggX := w.Hens[henIndex].X + HenWidth/2 - EggSize/2
+	baseSpeed := float64(1.0+float64(1.0*float64(w.Level-1))) * w.Difficulty.speed()
+	var vx, transitionX float64
+	if eggX < ScreenWidth/2 {
+		vx = baseSpeed / math.Sqrt(2)
+		transitionX = eggX + 67.5
+	} else {
+		vx = -baseSpeed / math.Sqrt(2)
+		transitionX = eggX - 67.5
+	}
+	w.addEgg(Egg{
+		X:           eggX,
+		Y:           w.Hens[henIndex].Y + float64(HenHeight),
+		VX:          vx,
+		VY:          2.0,
+		Phase:       "rolling",
+		TransitionX: transitionX,
+		Value:       valueEgg,
+		IsHarmful:   isHarmful,
+		Effect:      effect,
+	}, henIndex, ev)
+}
+
+// layEgg сбрасывает яйцо босса; fake делает его подделкой, иначе вид
+// яйца выбирается как у кур.
+func (w *World) layEgg(x, y, vx, vy float64, fake bool, ev *Events) {
+	egg := Egg{X: x, Y: y, VX: vx, VY: vy, Phase: "falling", TransitionX: x, IsHarmful: true}
+	if !fake {
+		egg.Value, egg.IsHarmful, egg.Effect = rollEgg(w.rng.Float64())
+	}
+	w.addEgg(egg, -1, ev)
+}
+
+// addEgg выдаёт яйцу номер и добавляет его в партию; hen — сбросившая
+// его курица, -1 у босса.
+func (w *World) addEgg(egg Egg, hen int, ev *Events) {
+	w.eggCount++
+	egg.ID = w.eggCount
+	egg.Active = true
+	ev.emit(Event{Type: EventEggLaid, Slot: -1, X: egg.X, Y: egg.Y, Value: egg.Value, Effect: egg.Effect, Hen: hen})
+	w.Eggs = append(w.Eggs, egg)
+}
+
+func (wf *Wolf) move(in Input) {
+	if wf.Out() {
+		wf.IsMoving = false
+		return
+	}
+	if in&InputLeft != 0 && wf.X > 0 {
+		wf.IsMoving = true
+		wf.X -= 5
+	} else if in&InputRight != 0 && wf.X < ScreenWidth-WolfWidth {
+		wf.IsMoving = true
+		wf.X += 5
+	} else {
+		wf.IsMoving = false
+	}
+}
+
+// moveWolves двигает волков; inputs[i] управляет волком i, недостающий
+// ввод считается пустым.
+func (w *World) moveWolves(inputs []Input) {
+	for i := range w.Wolves {
+		var in Input
+		if i < len(inputs) {
+			in = inputs[i]
+		}
+		w.Wolves[i].move(in)
+	}
+}
+
+func (wf *Wolf) inBasket(egg *Egg) bool {
+	half := wf.basketWidth() / 2
+	return egg.Y >= wf.BasketY && egg.Y <= wf.BasketY+BasketHeight &&
+		egg.X >= wf.X-half+WolfWidth/2 && egg.X <= wf.X+half+WolfWidth/2
+}
+
+// catchOrMiss проверяет, упало ли яйцо на землю или в чью-то корзину.
+// Если корзины пересекаются, яйцо достаётся первому по порядку волку.
+func (w *World) catchOrMiss(egg *Egg, ev *Events) {
+	if egg.Y > ScreenHeight {
+		egg.Active = false
+		if !egg.IsHarmful {
+			slot := w.nearestWolf(egg.X)
+			ev.emit(Event{Type: EventEggMissed, Slot: slot, X: egg.X, Y: ScreenHeight, Value: egg.Value, Effect: egg.Effect})
+			// Упущенный бонус жизни не стоит
+			if slot >= 0 && egg.Effect == EffectNone {
+				w.loseLife(slot, ev)
+			}
+		}
+	}
+	for i := range w.Wolves {
+		wf := &w.Wolves[i]
+		if wf.Out() || !wf.inBasket(egg) {
+			continue
+		}
+		egg.Active = false
+		if egg.IsHarmful && wf.Active(EffectShield) {
+			wf.Effects[EffectShield] = 0
+			ev.emit(Event{Type: EventShieldUsed, Slot: i, X: egg.X, Y: egg.Y})
+		} else if egg.IsHarmful {
+			w.loseLife(i, ev)
+		} else if egg.Effect != EffectNone {
+			wf.Effects[egg.Effect] = Effects[egg.Effect].Duration
+			wf.Caught++
+			wf.Combo++
+			wf.BestCombo = max(wf.BestCombo, wf.Combo)
+			ev.emit(Event{Type: EventEggCaught, Slot: i, X: egg.X, Y: egg.Y, Value: egg.Value, Effect: egg.Effect, Combo: wf.Combo})
+			if w.InBossRoom {
+				w.hitBoss(1, i, ev)
+			}
+		} else {
+			points := WhiteEggPoints
+			if egg.Value == 2 {
+				points = GoldEggPoints
+			}
+			points *= wf.Multiplier()
+			if wf.Active(EffectDoublePoints) {
+				points *= 2
+			}
+			wf.Score += points
+			wf.Caught++
+			wf.Combo++
+			wf.BestCombo = max(wf.BestCombo, wf.Combo)
+			ev.emit(Event{Type: EventEggCaught, Slot: i, X: egg.X, Y: egg.Y, Value: egg.Value, Points: points, Combo: wf.Combo})
+			if egg.Value == 1 && wf.Lives < MaxLives {
+				wf.Lives++
+				ev.emit(Event{Type: EventLifeGained, Slot: i})
+			}
+			// Пойманное в комнате босса яйцо бьёт по нему: золотое вдвое
+			if w.InBossRoom {
+				w.hitBoss(max(egg.Value, 1), i, ev)
+			}
+		}
+		break
+	}
+}
+
+// removeInactiveEggs убирает яйца на месте, не выделяя память каждый
+// кадр: снимки для сетевой игры копируют Eggs, а не хранят его.
+func (w *World) removeInactiveEggs() {
+	alive := w.Eggs[:0]
+	for _, egg := range w.Eggs {
+		if egg.Active {
+			alive = append(alive, egg)
+		}
+	}
+	w.Eggs = alive
+}
+
+// Step продвигает игру на один кадр (1/60 с); inputs[i] — ввод игрока i.
+// Игра заканчивается, когда выбыли все игроки. После окончания игры шаги
+// ничего не меняют.
+func (w *World) Step(inputs ...Input) Events {
+	var ev Events
+	if w.GameOver || w.GameWon {
+		return ev
+	}
+	w.Frame++
+	for i := range w.Wolves {
+		w.Wolves[i].tickEffects()
+	}
+
+	if !w.InBossRoom && w.TotalScore() >= w.bossScore() {
+		w.enterBossRoom(&ev)
+	}
+
+	if w.InBossRoom {
+		w.stepBossRoom(inputs, &ev)
+	} else {
+		w.stepMainStage(inputs, &ev)
+	}
+
+	if !w.GameWon && w.activeWolves() == 0 {
+		w.GameOver = true
+		ev.emit(Event{Type: EventGameOver, Slot: -1, Difficulty: w.Difficulty})
+	}
+	return ev
+}
+
+func (w *World) stepMainStage(inputs []Input, ev *Events) {
+	if w.TotalScore() > 10*w.Level && w.Level < MaxLevel {
+		w.Level++
+		ev.emit(Event{Type: EventLevelUp, Slot: -1, Level: w.Level})
+	}
+
+	w.moveWolves(inputs)
+
+	// Одновременно в полёте не больше яиц, чем игроков в игре
+	activeEggs := 0
+	for _, egg := range w.Eggs {
+		if egg.Active {
+			activeEggs++
+		}
+	}
+	if activeEggs < w.activeWolves() {
+		w.spawnEgg(ev)
+	}
+
+	// В замедлении яйца двигаются через кадр
+	if w.slowMotion() && w.Frame%2 == 1 {
+		return
+	}
+	for i := range w.Eggs {
+		egg := &w.Eggs[i]
+		if !egg.Active {
+			continue
+		}
+		if egg.Phase == "rolling" {
+			accel := float64(0.03+float64(0.03*float64(w.Level))) * w.Difficulty.speed()
+			if egg.VX > 0 {
+				egg.VX += accel / math.Sqrt(2)
+			} else {
+				egg.VX -= accel / math.Sqrt(2)
+			}
+			egg.VY += accel / math.Sqrt(2)
+			egg.X += egg.VX
+			egg.Y += egg.VY
+			if (egg.VX > 0 && egg.X >= egg.TransitionX) ||
+				(egg.VX < 0 && egg.X <= egg.TransitionX) {
+				egg.Phase = "falling"
+			}
+		} else {
+			egg.VY += float64(0.1 * w.Difficulty.speed())
+			vxFactor := 1.0
+			if egg.VX < 0 {
+				vxFactor = 0.75
+			}
+			egg.X += float64(egg.VX * vxFactor)
+			egg.Y += egg.VY
+			w.applyMagnet(egg)
+		}
+		w.catchOrMiss(egg, ev)
+	}
+	w.removeInactiveEggs()
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"io/fs"
+	"path"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"egg_catcher2/sim"
+)
+
+// animationInfo — описание анимации в avi/sprites.json: кадры листа по
+// порядку и сколько кадров игры показывается каждый.
+type animationInfo struct {
+	Frames    []int `json:"frames"`
+	FrameTime int   `json:"frame_time"`
+	Loop      bool  `json:"loop"`
+}
+
+type sheetInfo struct {
+	Image       string                   `json:"image"`
+	FrameWidth  int                      `json:"frame_width"`
+	FrameHeight int                      `json:"frame_height"`
+	Animations  map[string]animationInfo `json:"animations"`
+}
+
+// spriteSheet — лист кадров одного персонажа, нарезанный по размеру кадра.
+type spriteSheet struct {
+	frames     []*ebiten.Image
+	animations map[string]animationInfo
+	file       string // Картинка листа, за ней следит перезагрузка ресурсов
+}
+
+var (
+	sheetWolf *spriteSheet
+	sheetHen  *spriteSheet
+	sheetUfo  *spriteSheet // Летающая тарелка босса
+)
+
+// loadSprites читает описание листов из jsonPath и загружает листы из
+// того же каталога.
+func loadSprites(fsys fs.FS, jsonPath string) (map[string]*spriteSheet, error) {
+	data, err := fs.ReadFile(fsys, jsonPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", jsonPath, err)
+	}
+	var infos map[string]sheetInfo
+	if err := json.Unmarshal(data, &infos); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", jsonPath, err)
+	}
+	sheets := make(map[string]*spriteSheet, len(infos))
+	for name, info := range infos {
+		file := path.Join(path.Dir(jsonPath), info.Image)
+		img, err := loadImage(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		sheet := &spriteSheet{animations: info.Animations, file: file}
+		for x := 0; x+info.FrameWidth <= img.Bounds().Dx(); x += info.FrameWidth {
+			frame := img.SubImage(image.Rect(x, 0, x+info.FrameWidth, info.FrameHeight)).(*ebiten.Image)
+			sheet.frames = append(sheet.frames, frame)
+		}
+		for animName, anim := range info.Animations {
+			if anim.FrameTime <= 0 || len(anim.Frames) == 0 {
+				return nil, fmt.Errorf("animation %s/%s has no frames or frame time", name, animName)
+			}
+			for _, f := range anim.Frames {
+				if f < 0 || f >= len(sheet.frames) {
+					return nil, fmt.Errorf("animation %s/%s uses missing frame %d", name, animName, f)
+				}
+			}
+		}
+		sheets[name] = sheet
+	}
+	return sheets, nil
+}
+
+// animator проигрывает анимации одного листа. С nil-листом кадров нет, и
+// персонаж рисуется запасным прямоугольником.
+type animator struct {
+	sheet *spriteSheet
+	name  string
+	tick  int
+}
+
+func newAnimator(sheet *spriteSheet, name string) animator {
+	return animator{sheet: sheet, name: name}
+}
+
+// play переключает анимацию; текущая анимация не перезапускается.
+func (a *animator) play(name string) {
+	if a.name != name {
+		a.restart(name)
+	}
+}
+
+func (a *animator) restart(name string) {
+	a.name = name
+	a.tick = 0
+}
+
+func (a *animator) update() {
+	a.tick++
+}
+
+// finished сообщает, что неповторяющаяся анимация дошла до конца.
+func (a *animator) finished() bool {
+	if a.sheet == nil {
+		return true
+	}
+	anim := a.sheet.animations[a.name]
+	return !anim.Loop && a.tick >= len(anim.Frames)*anim.FrameTime
+}
+
+func (a *animator) frame() *ebiten.Image {
+	if a.sheet == nil || len(a.sheet.frames) == 0 {
+		return nil
+	}
+	anim, ok := a.sheet.animations[a.name]
+	if !ok {
+		return a.sheet.frames[0]
+	}
+	i := a.tick / anim.FrameTime
+	if anim.Loop {
+		i %= len(anim.Frames)
+	} else {
+		i = min(i, len(anim.Frames)-1)
+	}
+	return a.sheet.frames[anim.Frames[i]]
+}
+
+// spriteAnimations — анимации персонажей партии.
+type spriteAnimations struct {
+	wolves []animator
+	hens   [4]animator
+	boss   animator
+	bossID string // Босс, для которого создан boss
+}
+
+func newSpriteAnimations() *spriteAnimations {
+	s := &spriteAnimations{}
+	for i := range s.hens {
+		s.hens[i] = newAnimator(sheetHen, "idle")
+	}
+	return s
+}
+
+// update продвигает анимации на кадр симуляции: волк идёт, пока
+// движется, и стоит на месте, когда не доиграл ловлю и не двигается.
+func (s *spriteAnimations) update(w *sim.World) {
+	for len(s.wolves) < len(w.Wolves) {
+		s.wolves = append(s.wolves, newAnimator(sheetWolf, "idle"))
+	}
+	for i := range w.Wolves {
+		a := &s.wolves[i]
+		a.update()
+		if a.name == "catch" && !a.finished() {
+			continue
+		}
+		if w.Wolves[i].IsMoving {
+			a.play("walk")
+		} else {
+			a.play("idle")
+		}
+	}
+	for i := range s.hens {
+		a := &s.hens[i]
+		a.update()
+		if a.finished() {
+			a.play("idle")
+		}
+	}
+	if w.Boss != nil {
+		if s.bossID != w.Boss.Type {
+			t := w.Boss.Kind()
+			var sheet *spriteSheet
+			if g, ok := sheetGlobals[t.Sheet]; ok {
+				sheet = *g
+			}
+			s.boss = newAnimator(sheet, t.Animation)
+			s.bossID = w.Boss.Type
+		}
+		s.boss.update()
+	}
+}
+
+func (s *spriteAnimations) wolfFrame(slot int) *ebiten.Image {
+	if slot >= len(s.wolves) {
+		return sheetWolf.firstFrame()
+	}
+	return s.wolves[slot].frame()
+}
+
+func (sheet *spriteSheet) firstFrame() *ebiten.Image {
+	if sheet == nil || len(sheet.frames) == 0 {
+		return nil
+	}
+	return sheet.frames[0]
+}
+
+// subscribeSprites запускает анимацию ловли у поймавшего волка и
+// взмахи крыльями у курицы, снёсшей яйцо.
+func (g *Game) subscribeSprites(bus *sim.Bus) {
+	s := g.sprites
+	bus.Subscribe(sim.EventEggCaught, func(e sim.Event) {
+		if e.Slot < len(s.wolves) {
+			s.wolves[e.Slot].restart("catch")
+		}
+	})
+	bus.Subscribe(sim.EventEggLaid, func(e sim.Event) {
+		if e.Hen >= 0 {
+			s.hens[e.Hen].restart("flap")
+		}
+	})
+}
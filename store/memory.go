@@ -0,0 +1,442 @@
+package store
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"egg_catcher2/throttle"
+)
+
+type memoryGame struct {
+	playerID int
+	Game
+}
+
+type memoryDaily struct {
+	playerID int
+	day      string
+	finished bool
+	score    int
+	lives    int
+	replay   []byte
+	order    int // Порядок завершения для равных результатов
+}
+
+type Memory struct {
+	mu           sync.Mutex
+	nextID       int
+	players      map[int]Player
+	games        []memoryGame
+	bestRuns     map[int][]byte
+	daily        []memoryDaily
+	achievements map[int][]Achievement // По порядку получения
+	campaign     map[int]int           // Последний пройденный этап
+	tickets      map[int64]Ticket
+	sessions     map[string]Session
+	attempts     *throttle.MemoryStore
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		nextID:       1,
+		players:      make(map[int]Player),
+		sessions:     make(map[string]Session),
+		tickets:      make(map[int64]Ticket),
+		bestRuns:     make(map[int][]byte),
+		achievements: make(map[int][]Achievement),
+		campaign:     make(map[int]int),
+		attempts:     throttle.NewMemoryStore(),
+	}
+}
+
+func (m *Memory) CreatePlayer(name, passwordHash string) (Player, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range m.players {
+		if p.Name == name {
+			return Player{}, ErrNameTaken
+		}
+	}
+	p := Player{ID: m.nextID, Name: name, PasswordHash: passwordHash}
+	m.players[p.ID] = p
+	m.nextID++
+	return p, nil
+}
+
+func (m *Memory) PlayerByID(id int) (Player, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.players[id]
+	if !ok {
+		return Player{}, ErrNotFound
+	}
+	return p, nil
+}
+
+func (m *Memory) PlayerByName(name string) (Player, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range m.players {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return Player{}, ErrNotFound
+}
+
+func (m *Memory) SetPassword(playerID int, passwordHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.players[playerID]
+	if !ok {
+		return ErrNotFound
+	}
+	p.PasswordHash = passwordHash
+	m.players[playerID] = p
+	return nil
+}
+
+func (m *Memory) DeletePlayer(playerID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.players[playerID]; !ok {
+		return ErrNotFound
+	}
+	delete(m.players, playerID)
+	delete(m.bestRuns, playerID)
+	delete(m.achievements, playerID)
+	delete(m.campaign, playerID)
+	daily := m.daily[:0]
+	for _, d := range m.daily {
+		if d.playerID != playerID {
+			daily = append(daily, d)
+		}
+	}
+	m.daily = daily
+	games := m.games[:0]
+	for _, g := range m.games {
+		if g.playerID != playerID {
+			games = append(games, g)
+		}
+	}
+	m.games = games
+	for hash, s := range m.sessions {
+		if s.PlayerID == playerID {
+			delete(m.sessions, hash)
+		}
+	}
+	for nonce, t := range m.tickets {
+		if t.PlayerID == playerID {
+			delete(m.tickets, nonce)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) AddGame(playerID int, g Game) (Player, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.players[playerID]
+	if !ok {
+		return Player{}, ErrNotFound
+	}
+	replay := g.Replay
+	g.Replay = nil // Запись хранится только для лучшего забега
+	m.games = append(m.games, memoryGame{playerID: playerID, Game: g})
+	if replay != nil && (g.Score > p.HighScore || (m.bestRuns[playerID] == nil && g.Score >= p.HighScore)) {
+		m.bestRuns[playerID] = replay
+	}
+	if g.Score > p.HighScore {
+		p.HighScore = g.Score
+		m.players[playerID] = p
+	}
+	return p, nil
+}
+
+func (m *Memory) BestRun(playerID int) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	replay, ok := m.bestRuns[playerID]
+	if !ok || replay == nil {
+		return nil, ErrNotFound
+	}
+	return replay, nil
+}
+
+func (m *Memory) Leaderboard(limit int) ([]Player, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	leaderboard := make([]Player, 0, len(m.players))
+	for _, p := range m.players {
+		leaderboard = append(leaderboard, p)
+	}
+	sort.Slice(leaderboard, func(i, j int) bool {
+		if leaderboard[i].HighScore != leaderboard[j].HighScore {
+			return leaderboard[i].HighScore > leaderboard[j].HighScore
+		}
+		return leaderboard[i].ID < leaderboard[j].ID
+	})
+	if len(leaderboard) > limit {
+		leaderboard = leaderboard[:limit]
+	}
+	return leaderboard, nil
+}
+
+func (m *Memory) EggsCaught(playerID int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	eggs := 0
+	for _, g := range m.games {
+		if g.playerID == playerID {
+			eggs += g.EggsCaught
+		}
+	}
+	return eggs, nil
+}
+
+func (m *Memory) Stats(playerID, recent int) (Stats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var st Stats
+	var scores []int
+	total := 0
+	for _, g := range m.games {
+		if g.playerID != playerID {
+			continue
+		}
+		scores = append(scores, g.Score)
+		total += g.Score
+		st.EggsCaught += g.EggsCaught
+		st.BestLevel = max(st.BestLevel, g.Level)
+		if g.ReachedBoss {
+			st.BossFights++
+		}
+		if g.BossWon {
+			st.BossWins++
+		}
+	}
+	st.Games = len(scores)
+	if st.Games == 0 {
+		return st, nil
+	}
+	st.AverageScore = float64(total) / float64(st.Games)
+	st.RecentScores = slices.Clone(scores[max(0, len(scores)-recent):])
+	slices.Sort(scores)
+	mid := len(scores) / 2
+	if len(scores)%2 == 1 {
+		st.MedianScore = float64(scores[mid])
+	} else {
+		st.MedianScore = float64(scores[mid-1]+scores[mid]) / 2
+	}
+	return st, nil
+}
+
+func (m *Memory) UnlockAchievements(playerID int, ids []string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.players[playerID]; !ok {
+		return ErrNotFound
+	}
+	for _, id := range ids {
+		if !slices.ContainsFunc(m.achievements[playerID], func(a Achievement) bool { return a.ID == id }) {
+			m.achievements[playerID] = append(m.achievements[playerID], Achievement{ID: id, UnlockedAt: at})
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Achievements(playerID int) ([]Achievement, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.achievements[playerID]), nil
+}
+
+func (m *Memory) CampaignStage(playerID int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.campaign[playerID], nil
+}
+
+func (m *Memory) CompleteStage(playerID, stage int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.players[playerID]; !ok {
+		return ErrNotFound
+	}
+	m.campaign[playerID] = max(m.campaign[playerID], stage)
+	return nil
+}
+
+func (m *Memory) StartDaily(playerID int, day string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.players[playerID]; !ok {
+		return ErrNotFound
+	}
+	for _, d := range m.daily {
+		if d.playerID == playerID && d.day == day {
+			return ErrAlreadyPlayed
+		}
+	}
+	m.daily = append(m.daily, memoryDaily{playerID: playerID, day: day})
+	return nil
+}
+
+func (m *Memory) PendingDaily(playerID int) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	day := ""
+	for _, d := range m.daily {
+		if d.playerID == playerID && !d.finished && d.day > day {
+			day = d.day
+		}
+	}
+	if day == "" {
+		return "", ErrNotFound
+	}
+	return day, nil
+}
+
+func (m *Memory) FinishDaily(playerID int, day string, score, lives int, replay []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.daily {
+		d := &m.daily[i]
+		if d.playerID == playerID && d.day == day && !d.finished {
+			d.finished = true
+			d.score = score
+			d.lives = lives
+			d.replay = replay
+			d.order = i
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *Memory) DailyLeaderboard(day string, limit int) ([]DailyScore, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var entries []memoryDaily
+	for _, d := range m.daily {
+		if d.day == day && d.finished {
+			entries = append(entries, d)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].score != entries[j].score {
+			return entries[i].score > entries[j].score
+		}
+		return entries[i].order < entries[j].order
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	leaderboard := make([]DailyScore, 0, len(entries))
+	for _, d := range entries {
+		leaderboard = append(leaderboard, DailyScore{PlayerID: d.playerID, Name: m.players[d.playerID].Name, Day: d.day, Score: d.score})
+	}
+	return leaderboard, nil
+}
+
+func (m *Memory) CreateTicket(t Ticket) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.players[t.PlayerID]; !ok {
+		return ErrNotFound
+	}
+	m.tickets[t.Nonce] = t
+	return nil
+}
+
+func (m *Memory) Ticket(nonce int64) (Ticket, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tickets[nonce]
+	if !ok {
+		return Ticket{}, ErrNotFound
+	}
+	return t, nil
+}
+
+func (m *Memory) UseTicket(nonce int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tickets[nonce]
+	if !ok {
+		return ErrNotFound
+	}
+	if t.Used {
+		return ErrTicketUsed
+	}
+	t.Used = true
+	m.tickets[nonce] = t
+	return nil
+}
+
+func (m *Memory) DeleteTickets(before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for nonce, t := range m.tickets {
+		if t.IssuedAt.Before(before) {
+			delete(m.tickets, nonce)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) CreateSession(tokenHash string, s Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.players[s.PlayerID]; !ok {
+		return ErrNotFound
+	}
+	m.sessions[tokenHash] = s
+	return nil
+}
+
+func (m *Memory) SessionByToken(tokenHash string, now time.Time) (Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[tokenHash]
+	if !ok || !now.Before(s.ExpiresAt) {
+		return Session{}, ErrNotFound
+	}
+	return s, nil
+}
+
+func (m *Memory) ExtendSession(tokenHash string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[tokenHash]
+	if !ok {
+		return ErrNotFound
+	}
+	s.ExpiresAt = expiresAt
+	m.sessions[tokenHash] = s
+	return nil
+}
+
+func (m *Memory) DeleteSession(tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, tokenHash)
+	return nil
+}
+
+func (m *Memory) DeleteSessions(playerID int, keepHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for hash, s := range m.sessions {
+		if s.PlayerID == playerID && hash != keepHash {
+			delete(m.sessions, hash)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Attempts() throttle.Store {
+	return m.attempts
+}
@@ -0,0 +1,550 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq" // PostgreSQL driver
+
+	"egg_catcher2/throttle"
+)
+
+type SQL struct {
+	db       *sql.DB
+	attempts *throttle.SQLStore
+}
+
+func Open(dbURL string) (*SQL, error) {
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %v", err)
+	}
+	s, err := NewSQL(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func NewSQL(db *sql.DB) (*SQL, error) {
+	_, err := db.Exec(`
+CREATE TABLE IF NOT EXISTS players (
+id SERIAL PRIMARY KEY,
+name TEXT NOT NULL UNIQUE,
+high_score INTEGER DEFAULT 0,
+password TEXT NOT NULL
+)
+`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create players table: %v", err)
+	}
+	_, err = db.Exec("ALTER TABLE players ADD COLUMN IF NOT EXISTS best_replay BYTEA")
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate players table: %v", err)
+	}
+	_, err = db.Exec(`
+CREATE TABLE IF NOT EXISTS games (
+id SERIAL PRIMARY KEY,
+player_id INTEGER NOT NULL,
+score INTEGER NOT NULL,
+lives INTEGER NOT NULL,
+date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE
+)
+`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create games table: %v", err)
+	}
+	_, err = db.Exec("ALTER TABLE games ADD COLUMN IF NOT EXISTS best_combo INTEGER NOT NULL DEFAULT 0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate games table: %v", err)
+	}
+	_, err = db.Exec("ALTER TABLE games ADD COLUMN IF NOT EXISTS eggs_caught INTEGER NOT NULL DEFAULT 0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate games table: %v", err)
+	}
+	_, err = db.Exec("ALTER TABLE games ADD COLUMN IF NOT EXISTS level INTEGER NOT NULL DEFAULT 1")
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate games table: %v", err)
+	}
+	_, err = db.Exec("ALTER TABLE games ADD COLUMN IF NOT EXISTS reached_boss BOOLEAN NOT NULL DEFAULT FALSE")
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate games table: %v", err)
+	}
+	_, err = db.Exec("ALTER TABLE games ADD COLUMN IF NOT EXISTS boss_won BOOLEAN NOT NULL DEFAULT FALSE")
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate games table: %v", err)
+	}
+	_, err = db.Exec(`
+CREATE TABLE IF NOT EXISTS player_achievements (
+player_id INTEGER NOT NULL,
+achievement TEXT NOT NULL,
+unlocked_at TIMESTAMP NOT NULL,
+PRIMARY KEY (player_id, achievement),
+FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE
+)
+`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create player_achievements table: %v", err)
+	}
+	_, err = db.Exec(`
+CREATE TABLE IF NOT EXISTS sessions (
+id SERIAL PRIMARY KEY,
+player_id INTEGER NOT NULL,
+token_hash TEXT NOT NULL UNIQUE,
+created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+last_used TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+expires_at TIMESTAMP NOT NULL,
+FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE
+)
+`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sessions table: %v", err)
+	}
+	_, err = db.Exec("ALTER TABLE sessions ADD COLUMN IF NOT EXISTS persistent BOOLEAN NOT NULL DEFAULT TRUE")
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate sessions table: %v", err)
+	}
+	if _, err := db.Exec("DELETE FROM sessions WHERE expires_at < CURRENT_TIMESTAMP"); err != nil {
+		return nil, fmt.Errorf("failed to remove expired sessions: %v", err)
+	}
+	_, err = db.Exec(`
+CREATE TABLE IF NOT EXISTS daily_scores (
+player_id INTEGER NOT NULL,
+day DATE NOT NULL,
+score INTEGER,
+lives INTEGER,
+replay BYTEA,
+started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+finished_at TIMESTAMP,
+PRIMARY KEY (player_id, day),
+FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE
+)
+`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create daily_scores table: %v", err)
+	}
+	_, err = db.Exec(`
+CREATE TABLE IF NOT EXISTS campaign_progress (
+player_id INTEGER PRIMARY KEY,
+stage INTEGER NOT NULL,
+updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE
+)
+`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create campaign_progress table: %v", err)
+	}
+	_, err = db.Exec(`
+CREATE TABLE IF NOT EXISTS game_tickets (
+nonce BIGINT PRIMARY KEY,
+player_id INTEGER NOT NULL,
+seed BIGINT NOT NULL,
+issued_at TIMESTAMP NOT NULL,
+used BOOLEAN NOT NULL DEFAULT FALSE,
+FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE CASCADE
+)
+`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create game_tickets table: %v", err)
+	}
+	attempts, err := throttle.NewSQLStore(db)
+	if err != nil {
+		return nil, err
+	}
+	return &SQL{db: db, attempts: attempts}, nil
+}
+
+func (s *SQL) Close() error {
+	return s.db.Close()
+}
+
+// Clear удаляет все данные, оставляя схему.
+func (s *SQL) Clear() error {
+	_, err := s.db.Exec("TRUNCATE TABLE games, sessions, daily_scores, player_achievements, campaign_progress, game_tickets, login_attempts, players RESTART IDENTITY CASCADE")
+	if err != nil {
+		return fmt.Errorf("failed to clear tables: %v", err)
+	}
+	return nil
+}
+
+func (s *SQL) CreatePlayer(name, passwordHash string) (Player, error) {
+	p := Player{Name: name, PasswordHash: passwordHash}
+	err := s.db.QueryRow("INSERT INTO players (name, high_score, password) VALUES ($1, 0, $2) RETURNING id", name, passwordHash).Scan(&p.ID)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return Player{}, ErrNameTaken
+	}
+	if err != nil {
+		return Player{}, fmt.Errorf("failed to insert new player: %v", err)
+	}
+	return p, nil
+}
+
+func (s *SQL) scanPlayer(row *sql.Row) (Player, error) {
+	var p Player
+	err := row.Scan(&p.ID, &p.Name, &p.HighScore, &p.PasswordHash)
+	if err == sql.ErrNoRows {
+		return Player{}, ErrNotFound
+	}
+	if err != nil {
+		return Player{}, fmt.Errorf("failed to get player data: %v", err)
+	}
+	return p, nil
+}
+
+func (s *SQL) PlayerByID(id int) (Player, error) {
+	return s.scanPlayer(s.db.QueryRow("SELECT id, name, high_score, password FROM players WHERE id = $1", id))
+}
+
+func (s *SQL) PlayerByName(name string) (Player, error) {
+	return s.scanPlayer(s.db.QueryRow("SELECT id, name, high_score, password FROM players WHERE name = $1", name))
+}
+
+func (s *SQL) SetPassword(playerID int, passwordHash string) error {
+	res, err := s.db.Exec("UPDATE players SET password = $1 WHERE id = $2", passwordHash, playerID)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQL) DeletePlayer(playerID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+	// Таблицы, созданные старыми версиями, могли остаться без ON DELETE CASCADE
+	if _, err := tx.Exec("DELETE FROM games WHERE player_id = $1", playerID); err != nil {
+		return fmt.Errorf("failed to delete games: %v", err)
+	}
+	if _, err := tx.Exec("DELETE FROM sessions WHERE player_id = $1", playerID); err != nil {
+		return fmt.Errorf("failed to delete sessions: %v", err)
+	}
+	res, err := tx.Exec("DELETE FROM players WHERE id = $1", playerID)
+	if err != nil {
+		return fmt.Errorf("failed to delete player: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit account deletion: %v", err)
+	}
+	return nil
+}
+
+func (s *SQL) AddGame(playerID int, g Game) (Player, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return Player{}, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`
+INSERT INTO games (player_id, score, lives, best_combo, eggs_caught, level, reached_boss, boss_won)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		playerID, g.Score, g.Lives, g.BestCombo, g.EggsCaught, g.Level, g.ReachedBoss, g.BossWon); err != nil {
+		return Player{}, fmt.Errorf("failed to save game data: %v", err)
+	}
+	var p Player
+	// В SET все выражения видят старые значения строки, поэтому CASE
+	// сравнивает со старым рекордом
+	err = tx.QueryRow(`
+UPDATE players SET
+best_replay = CASE WHEN $3::BYTEA IS NOT NULL AND ($1 > high_score OR (best_replay IS NULL AND $1 >= high_score)) THEN $3 ELSE best_replay END,
+high_score = GREATEST(high_score, $1)
+WHERE id = $2
+RETURNING id, name, high_score, password`,
+		g.Score, playerID, g.Replay).Scan(&p.ID, &p.Name, &p.HighScore, &p.PasswordHash)
+	if err == sql.ErrNoRows {
+		return Player{}, ErrNotFound
+	}
+	if err != nil {
+		return Player{}, fmt.Errorf("failed to update high score: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return Player{}, fmt.Errorf("failed to commit game data: %v", err)
+	}
+	return p, nil
+}
+
+func (s *SQL) BestRun(playerID int) ([]byte, error) {
+	var replay []byte
+	err := s.db.QueryRow("SELECT best_replay FROM players WHERE id = $1", playerID).Scan(&replay)
+	if err == sql.ErrNoRows || (err == nil && replay == nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load best run: %v", err)
+	}
+	return replay, nil
+}
+
+func (s *SQL) Leaderboard(limit int) ([]Player, error) {
+	rows, err := s.db.Query("SELECT id, name, high_score FROM players ORDER BY high_score DESC, id LIMIT $1", limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load leaderboard: %v", err)
+	}
+	defer rows.Close()
+	var leaderboard []Player
+	for rows.Next() {
+		var p Player
+		if err := rows.Scan(&p.ID, &p.Name, &p.HighScore); err != nil {
+			return nil, fmt.Errorf("failed to scan leaderboard row: %v", err)
+		}
+		leaderboard = append(leaderboard, p)
+	}
+	return leaderboard, rows.Err()
+}
+
+func (s *SQL) EggsCaught(playerID int) (int, error) {
+	var eggs int
+	err := s.db.QueryRow("SELECT COALESCE(SUM(eggs_caught), 0) FROM games WHERE player_id = $1", playerID).Scan(&eggs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count caught eggs: %v", err)
+	}
+	return eggs, nil
+}
+
+func (s *SQL) Stats(playerID, recent int) (Stats, error) {
+	var st Stats
+	err := s.db.QueryRow(`
+SELECT COUNT(*), COALESCE(AVG(score), 0), COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY score), 0),
+COALESCE(SUM(eggs_caught), 0), COALESCE(MAX(level), 0),
+COUNT(*) FILTER (WHERE reached_boss), COUNT(*) FILTER (WHERE boss_won)
+FROM games WHERE player_id = $1`, playerID).Scan(
+		&st.Games, &st.AverageScore, &st.MedianScore, &st.EggsCaught, &st.BestLevel, &st.BossFights, &st.BossWins)
+	if err != nil {
+		return Stats{}, fmt.Errorf("failed to load player stats: %v", err)
+	}
+	rows, err := s.db.Query(`
+SELECT score FROM (SELECT id, score FROM games WHERE player_id = $1 ORDER BY id DESC LIMIT $2) recent
+ORDER BY id`, playerID, recent)
+	if err != nil {
+		return Stats{}, fmt.Errorf("failed to load recent scores: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var score int
+		if err := rows.Scan(&score); err != nil {
+			return Stats{}, fmt.Errorf("failed to scan recent score: %v", err)
+		}
+		st.RecentScores = append(st.RecentScores, score)
+	}
+	return st, rows.Err()
+}
+
+func (s *SQL) UnlockAchievements(playerID int, ids []string, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := s.db.Exec(`
+INSERT INTO player_achievements (player_id, achievement, unlocked_at)
+SELECT $1, unnest($2::TEXT[]), $3
+ON CONFLICT DO NOTHING`, playerID, pq.Array(ids), at)
+	if err != nil {
+		return fmt.Errorf("failed to save achievements: %v", err)
+	}
+	return nil
+}
+
+func (s *SQL) Achievements(playerID int) ([]Achievement, error) {
+	rows, err := s.db.Query("SELECT achievement, unlocked_at FROM player_achievements WHERE player_id = $1 ORDER BY unlocked_at, achievement", playerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load achievements: %v", err)
+	}
+	defer rows.Close()
+	var achievements []Achievement
+	for rows.Next() {
+		var a Achievement
+		if err := rows.Scan(&a.ID, &a.UnlockedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan achievement row: %v", err)
+		}
+		achievements = append(achievements, a)
+	}
+	return achievements, rows.Err()
+}
+
+func (s *SQL) CampaignStage(playerID int) (int, error) {
+	var stage int
+	err := s.db.QueryRow("SELECT stage FROM campaign_progress WHERE player_id = $1", playerID).Scan(&stage)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to load campaign progress: %v", err)
+	}
+	return stage, nil
+}
+
+func (s *SQL) CompleteStage(playerID, stage int) error {
+	_, err := s.db.Exec(`
+INSERT INTO campaign_progress (player_id, stage) VALUES ($1, $2)
+ON CONFLICT (player_id) DO UPDATE SET
+stage = GREATEST(campaign_progress.stage, EXCLUDED.stage), updated_at = CURRENT_TIMESTAMP`, playerID, stage)
+	if err != nil {
+		return fmt.Errorf("failed to save campaign progress: %v", err)
+	}
+	return nil
+}
+
+func (s *SQL) StartDaily(playerID int, day string) error {
+	res, err := s.db.Exec("INSERT INTO daily_scores (player_id, day) VALUES ($1, $2) ON CONFLICT DO NOTHING", playerID, day)
+	if err != nil {
+		return fmt.Errorf("failed to start daily challenge: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrAlreadyPlayed
+	}
+	return nil
+}
+
+func (s *SQL) PendingDaily(playerID int) (string, error) {
+	var day string
+	err := s.db.QueryRow("SELECT to_char(day, 'YYYY-MM-DD') FROM daily_scores WHERE player_id = $1 AND score IS NULL ORDER BY day DESC LIMIT 1",
+		playerID).Scan(&day)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to find daily attempt: %v", err)
+	}
+	return day, nil
+}
+
+func (s *SQL) FinishDaily(playerID int, day string, score, lives int, replay []byte) error {
+	res, err := s.db.Exec(`
+UPDATE daily_scores SET score = $3, lives = $4, replay = $5, finished_at = CURRENT_TIMESTAMP
+WHERE player_id = $1 AND day = $2 AND score IS NULL`, playerID, day, score, lives, replay)
+	if err != nil {
+		return fmt.Errorf("failed to save daily score: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQL) DailyLeaderboard(day string, limit int) ([]DailyScore, error) {
+	rows, err := s.db.Query(`
+SELECT d.player_id, p.name, to_char(d.day, 'YYYY-MM-DD'), d.score
+FROM daily_scores d JOIN players p ON p.id = d.player_id
+WHERE d.day = $1 AND d.score IS NOT NULL
+ORDER BY d.score DESC, d.finished_at
+LIMIT $2`, day, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load daily leaderboard: %v", err)
+	}
+	defer rows.Close()
+	var leaderboard []DailyScore
+	for rows.Next() {
+		var d DailyScore
+		if err := rows.Scan(&d.PlayerID, &d.Name, &d.Day, &d.Score); err != nil {
+			return nil, fmt.Errorf("failed to scan daily leaderboard row: %v", err)
+		}
+		leaderboard = append(leaderboard, d)
+	}
+	return leaderboard, rows.Err()
+}
+
+func (s *SQL) CreateTicket(t Ticket) error {
+	_, err := s.db.Exec("INSERT INTO game_tickets (nonce, player_id, seed, issued_at) VALUES ($1, $2, $3, $4)",
+		t.Nonce, t.PlayerID, t.Seed, t.IssuedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create game ticket: %v", err)
+	}
+	return nil
+}
+
+func (s *SQL) Ticket(nonce int64) (Ticket, error) {
+	t := Ticket{Nonce: nonce}
+	err := s.db.QueryRow("SELECT player_id, seed, issued_at, used FROM game_tickets WHERE nonce = $1", nonce).
+		Scan(&t.PlayerID, &t.Seed, &t.IssuedAt, &t.Used)
+	if err == sql.ErrNoRows {
+		return Ticket{}, ErrNotFound
+	}
+	if err != nil {
+		return Ticket{}, fmt.Errorf("failed to look up game ticket: %v", err)
+	}
+	return t, nil
+}
+
+func (s *SQL) UseTicket(nonce int64) error {
+	res, err := s.db.Exec("UPDATE game_tickets SET used = TRUE WHERE nonce = $1 AND NOT used", nonce)
+	if err != nil {
+		return fmt.Errorf("failed to use game ticket: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := s.Ticket(nonce); err != nil {
+			return err
+		}
+		return ErrTicketUsed
+	}
+	return nil
+}
+
+func (s *SQL) DeleteTickets(before time.Time) error {
+	if _, err := s.db.Exec("DELETE FROM game_tickets WHERE issued_at < $1", before); err != nil {
+		return fmt.Errorf("failed to delete old game tickets: %v", err)
+	}
+	return nil
+}
+
+func (s *SQL) CreateSession(tokenHash string, sess Session) error {
+	_, err := s.db.Exec("INSERT INTO sessions (player_id, token_hash, expires_at, persistent) VALUES ($1, $2, $3, $4)",
+		sess.PlayerID, tokenHash, sess.ExpiresAt, sess.Persistent)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %v", err)
+	}
+	return nil
+}
+
+func (s *SQL) SessionByToken(tokenHash string, now time.Time) (Session, error) {
+	var sess Session
+	err := s.db.QueryRow("SELECT player_id, persistent, expires_at FROM sessions WHERE token_hash = $1 AND expires_at > $2",
+		tokenHash, now).Scan(&sess.PlayerID, &sess.Persistent, &sess.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return Session{}, ErrNotFound
+	}
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to look up session: %v", err)
+	}
+	return sess, nil
+}
+
+func (s *SQL) ExtendSession(tokenHash string, expiresAt time.Time) error {
+	_, err := s.db.Exec("UPDATE sessions SET last_used = CURRENT_TIMESTAMP, expires_at = $1 WHERE token_hash = $2", expiresAt, tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to extend session: %v", err)
+	}
+	return nil
+}
+
+func (s *SQL) DeleteSession(tokenHash string) error {
+	if _, err := s.db.Exec("DELETE FROM sessions WHERE token_hash = $1", tokenHash); err != nil {
+		return fmt.Errorf("failed to revoke session: %v", err)
+	}
+	return nil
+}
+
+func (s *SQL) DeleteSessions(playerID int, keepHash string) error {
+	if _, err := s.db.Exec("DELETE FROM sessions WHERE player_id = $1 AND token_hash <> $2", playerID, keepHash); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %v", err)
+	}
+	return nil
+}
+
+func (s *SQL) Attempts() throttle.Store {
+	return s.attempts
+}
@@ -0,0 +1,138 @@
+// Package store хранит игроков, партии и сессии. SQL реализует его
+// поверх PostgreSQL, Memory держит всё в памяти для тестов и локального сервера.
+package store
+
+import (
+	"errors"
+	"time"
+
+	"egg_catcher2/throttle"
+)
+
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrNameTaken     = errors.New("username already taken")
+	ErrAlreadyPlayed = errors.New("daily challenge already played")
+	ErrTicketUsed    = errors.New("game ticket already used")
+)
+
+type Player struct {
+	ID           int
+	Name         string
+	HighScore    int
+	PasswordHash string
+}
+
+// DailyScore — результат ежедневного испытания. Day в формате 2006-01-02 (UTC).
+type DailyScore struct {
+	PlayerID int
+	Name     string
+	Day      string
+	Score    int
+}
+
+// Game — итог одной партии.
+type Game struct {
+	Score       int
+	Lives       int
+	BestCombo   int
+	EggsCaught  int
+	Level       int  // Уровень, до которого дошёл игрок
+	ReachedBoss bool // Игрок дошёл до комнаты босса
+	BossWon     bool
+	Replay      []byte // nil, если партия не может стать лучшим забегом
+}
+
+// Stats — статистика игрока по всем его партиям.
+type Stats struct {
+	Games        int
+	AverageScore float64
+	MedianScore  float64
+	EggsCaught   int
+	BestLevel    int
+	BossFights   int // Партии, дошедшие до босса
+	BossWins     int
+	RecentScores []int // Счёт последних партий, от старых к новым
+}
+
+// Achievement — полученное игроком достижение.
+type Achievement struct {
+	ID         string
+	UnlockedAt time.Time
+}
+
+// Ticket — билет партии, выданный сервером игроку. Партия идёт на Seed
+// из билета, а результат по билету принимается только один раз.
+type Ticket struct {
+	Nonce    int64
+	PlayerID int
+	Seed     int64
+	IssuedAt time.Time
+	Used     bool
+}
+
+type Session struct {
+	PlayerID   int
+	Persistent bool // Сессия "remember me"
+	ExpiresAt  time.Time
+}
+
+type Store interface {
+	CreatePlayer(name, passwordHash string) (Player, error)
+	PlayerByID(id int) (Player, error)
+	PlayerByName(name string) (Player, error)
+	SetPassword(playerID int, passwordHash string) error
+	// DeletePlayer удаляет игрока вместе с его партиями и сессиями.
+	DeletePlayer(playerID int) error
+	// AddGame записывает партию и обновляет рекорд игрока. Запись партии
+	// сохраняется как лучший забег, если рекорд побит; nil оставляет
+	// прежний лучший забег.
+	AddGame(playerID int, g Game) (Player, error)
+	// BestRun возвращает запись партии, установившей рекорд, или ErrNotFound.
+	BestRun(playerID int) ([]byte, error)
+	Leaderboard(limit int) ([]Player, error)
+	// EggsCaught возвращает число яиц, пойманных игроком во всех партиях.
+	EggsCaught(playerID int) (int, error)
+	// Stats считает статистику по партиям игрока; в RecentScores попадают
+	// последние recent партий.
+	Stats(playerID, recent int) (Stats, error)
+
+	// UnlockAchievements засчитывает достижения; уже полученные
+	// пропускаются и сохраняют прежнее время.
+	UnlockAchievements(playerID int, ids []string, at time.Time) error
+	// Achievements возвращает достижения игрока в порядке получения.
+	Achievements(playerID int) ([]Achievement, error)
+
+	// CampaignStage возвращает последний пройденный игроком этап кампании,
+	// 0 — если он не прошёл ни одного.
+	CampaignStage(playerID int) (int, error)
+	// CompleteStage отмечает этап пройденным; прохождение более раннего
+	// этапа прогресс не уменьшает.
+	CompleteStage(playerID, stage int) error
+
+	// StartDaily засчитывает попытку ежедневного испытания ещё до её
+	// окончания; повторный старт в тот же день возвращает ErrAlreadyPlayed.
+	StartDaily(playerID int, day string) error
+	// PendingDaily возвращает день начатой, но не завершённой попытки.
+	PendingDaily(playerID int) (string, error)
+	FinishDaily(playerID int, day string, score, lives int, replay []byte) error
+	DailyLeaderboard(day string, limit int) ([]DailyScore, error)
+
+	CreateTicket(t Ticket) error
+	// Ticket возвращает билет по Nonce или ErrNotFound.
+	Ticket(nonce int64) (Ticket, error)
+	// UseTicket отмечает билет использованным; повторно — ErrTicketUsed.
+	UseTicket(nonce int64) error
+	// DeleteTickets удаляет билеты, выданные раньше before.
+	DeleteTickets(before time.Time) error
+
+	CreateSession(tokenHash string, s Session) error
+	// SessionByToken возвращает ErrNotFound для отозванных и истёкших сессий.
+	SessionByToken(tokenHash string, now time.Time) (Session, error)
+	ExtendSession(tokenHash string, expiresAt time.Time) error
+	DeleteSession(tokenHash string) error
+	// DeleteSessions удаляет все сессии игрока, кроме keepHash.
+	DeleteSessions(playerID int, keepHash string) error
+
+	Attempts() throttle.Store
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Темы меняют картинки и музыку игры. Встроенные лежат в themes/ рядом с
+// assets.json, свои можно положить в каталог настроек, в themes/, папкой
+// или zip-архивом с assets.json в корне. Любая тема, кроме классической,
+// может не перечислять часть ресурсов — тогда они берутся из классической.
+// Встроенные winter и space своей музыки не везут и звучат как классическая.
+// Недостающие файлы Draw рисует цветными прямоугольниками, как и без
+// картинок вообще.
+
+const defaultTheme = "classic"
+
+// theme — тема, которую можно выбрать на экране профиля.
+type theme struct {
+	name     string
+	fsys     fs.FS  // Встроенные файлы темы
+	manifest string // Путь к манифесту внутри темы
+	path     string // Папка или архив темы на диске, заменяет fsys
+}
+
+// currentTheme — имя выбранной темы.
+var currentTheme = defaultTheme
+
+// assetsDir — каталог из флага -assets, который заменяет встроенные
+// файлы всех встроенных тем. devMode перезагружает изменённые файлы тем
+// из каталогов.
+var (
+	assetsDir string
+	devMode   bool
+)
+
+func themesDir() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "themes"), nil
+}
+
+// listThemes возвращает сначала встроенные темы, потом темы из каталога
+// настроек.
+func listThemes() []theme {
+	classic := theme{name: defaultTheme, fsys: assetFiles, manifest: manifestPath, path: assetsDir}
+	themes := []theme{classic}
+	builtin, err := fs.ReadDir(assetFiles, "themes")
+	if err != nil {
+		log.Printf("Error listing built-in themes: %v", err)
+	}
+	for _, e := range builtin {
+		themes = append(themes, theme{
+			name:     e.Name(),
+			fsys:     assetFiles,
+			manifest: path.Join("themes", e.Name(), manifestPath),
+			path:     assetsDir,
+		})
+	}
+
+	dir, err := themesDir()
+	if err != nil {
+		return themes
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error listing themes: %v", err)
+		}
+		return themes
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() {
+			if !strings.HasSuffix(name, ".zip") {
+				continue
+			}
+			name = strings.TrimSuffix(name, ".zip")
+		}
+		themes = append(themes, theme{name: name, manifest: manifestPath, path: filepath.Join(dir, e.Name())})
+	}
+	return themes
+}
+
+func findTheme(name string) (theme, bool) {
+	for _, t := range listThemes() {
+		if t.name == name {
+			return t, true
+		}
+	}
+	return theme{}, false
+}
+
+// load загружает ресурсы темы. Архив остаётся открытым, пока тема
+// выбрана: картинки и звуки читаются из него при загрузке.
+func (t theme) load() (*resources, error) {
+	fsys := t.fsys
+	var zr *zip.ReadCloser
+	switch {
+	case strings.HasSuffix(t.path, ".zip"):
+		var err error
+		if zr, err = zip.OpenReader(t.path); err != nil {
+			return nil, fmt.Errorf("failed to open theme %s: %v", t.name, err)
+		}
+		fsys = zr
+	case t.path != "":
+		fsys = os.DirFS(t.path)
+	}
+	// Архив и встроенные файлы не меняются, следить стоит только за папкой
+	hotReload := devMode && zr == nil && t.path != ""
+	if devMode && !hotReload {
+		log.Printf("Hot reload needs a theme directory, ignoring it for theme %s", t.name)
+	}
+	// Чего нет в теме, берётся из классической, её файлы заменяет -assets
+	var base fs.FS
+	if t.name != defaultTheme {
+		base = assetFiles
+		if assetsDir != "" {
+			base = os.DirFS(assetsDir)
+		}
+	}
+	r, err := loadResources(fsys, base, t.manifest, hotReload)
+	if err != nil {
+		if zr != nil {
+			zr.Close()
+		}
+		return nil, fmt.Errorf("error loading theme %s: %v", t.name, err)
+	}
+	if zr != nil {
+		r.closer = zr
+	}
+	return r, nil
+}
+
+// themeTitle — название выбранной темы для экрана профиля.
+func themeTitle() string {
+	if assets != nil && assets.manifest.Name != "" {
+		return assets.manifest.Name
+	}
+	return currentTheme
+}
+
+// loadStartTheme загружает тему, выбранную в прошлый раз, а если её
+// больше нет или она не загрузилась — классическую.
+func loadStartTheme() (*resources, error) {
+	name := loadThemeSetting()
+	if name != defaultTheme {
+		if t, ok := findTheme(name); !ok {
+			log.Printf("Theme %s not found, using %s", name, defaultTheme)
+		} else if r, err := t.load(); err != nil {
+			log.Printf("%v, using %s", err, defaultTheme)
+		} else {
+			currentTheme = name
+			return r, nil
+		}
+	}
+	t, _ := findTheme(defaultTheme)
+	return t.load()
+}
+
+// switchTheme заменяет ресурсы игры ресурсами темы name и запоминает
+// выбор. Музыка меню продолжает играть уже из новой темы.
+func switchTheme(name string) error {
+	t, ok := findTheme(name)
+	if !ok {
+		return fmt.Errorf("theme %s not found", name)
+	}
+	playing := player != nil && player.IsPlaying()
+	old := assets
+	r, err := t.load()
+	if err != nil {
+		return err
+	}
+	assets = r
+	currentTheme = name
+	old.close()
+	if playing && player != nil {
+		player.Play()
+	}
+	if err := saveThemeSetting(name); err != nil {
+		log.Printf("Error saving theme: %v", err)
+	}
+	return nil
+}
+
+// nextTheme возвращает тему, следующую за темой after, по кругу.
+func nextTheme(after string) string {
+	themes := listThemes()
+	for i, t := range themes {
+		if t.name == after && i+1 < len(themes) {
+			return themes[i+1].name
+		}
+	}
+	return themes[0].name
+}
+
+func themeFile() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "theme"), nil
+}
+
+func saveThemeSetting(name string) error {
+	path, err := themeFile()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(name), 0o600); err != nil {
+		return fmt.Errorf("failed to save theme: %v", err)
+	}
+	return nil
+}
+
+func loadThemeSetting() string {
+	path, err := themeFile()
+	if err != nil {
+		return defaultTheme
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return defaultTheme
+	}
+	if name := strings.TrimSpace(string(data)); name != "" {
+		return name
+	}
+	return defaultTheme
+}
@@ -0,0 +1,85 @@
+package throttle
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]Record)}
+}
+
+func (s *MemoryStore) Get(key string) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.records[key], nil
+}
+
+func (s *MemoryStore) Put(key string, rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = rec
+	return nil
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+type SQLStore struct {
+	db *sql.DB
+}
+
+func NewSQLStore(db *sql.DB) (*SQLStore, error) {
+	_, err := db.Exec(`
+CREATE TABLE IF NOT EXISTS login_attempts (
+key TEXT PRIMARY KEY,
+failures INTEGER NOT NULL DEFAULT 0,
+last_failure TIMESTAMPTZ,
+locked_until TIMESTAMPTZ
+)
+`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create login_attempts table: %v", err)
+	}
+	return &SQLStore{db: db}, nil
+}
+
+func (s *SQLStore) Get(key string) (Record, error) {
+	var rec Record
+	var lastFailure, lockedUntil sql.NullTime
+	err := s.db.QueryRow("SELECT failures, last_failure, locked_until FROM login_attempts WHERE key = $1", key).
+		Scan(&rec.Failures, &lastFailure, &lockedUntil)
+	if err == sql.ErrNoRows {
+		return Record{}, nil
+	}
+	if err != nil {
+		return Record{}, err
+	}
+	rec.LastFailure = lastFailure.Time
+	rec.LockedUntil = lockedUntil.Time
+	return rec, nil
+}
+
+func (s *SQLStore) Put(key string, rec Record) error {
+	lockedUntil := sql.NullTime{Time: rec.LockedUntil, Valid: !rec.LockedUntil.IsZero()}
+	_, err := s.db.Exec(`
+INSERT INTO login_attempts (key, failures, last_failure, locked_until) VALUES ($1, $2, $3, $4)
+ON CONFLICT (key) DO UPDATE SET failures = $2, last_failure = $3, locked_until = $4
+`, key, rec.Failures, rec.LastFailure, lockedUntil)
+	return err
+}
+
+func (s *SQLStore) Delete(key string) error {
+	_, err := s.db.Exec("DELETE FROM login_attempts WHERE key = $1", key)
+	return err
+}
@@ -0,0 +1,136 @@
+// Package throttle ограничивает частоту попыток входа: после каждой
+// неудачи следующая попытка откладывается экспоненциально, а после
+// MaxFailures неудач ключ блокируется на время Lockout.
+package throttle
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Record хранит историю неудачных попыток для одного ключа
+// (имени пользователя или клиента).
+type Record struct {
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
+
+// Store сохраняет счётчики попыток. Отсутствующий ключ возвращает
+// нулевой Record без ошибки.
+type Store interface {
+	Get(key string) (Record, error)
+	Put(key string, rec Record) error
+	Delete(key string) error
+}
+
+// LimitError возвращается, когда попытка сделана раньше разрешённого времени.
+type LimitError struct {
+	Wait   time.Duration
+	Locked bool
+}
+
+func (e *LimitError) Error() string {
+	seconds := int(math.Ceil(e.Wait.Seconds()))
+	if e.Locked {
+		return fmt.Sprintf("too many failed attempts, locked for %d s", seconds)
+	}
+	return fmt.Sprintf("too many attempts, try again in %d s", seconds)
+}
+
+type Limiter struct {
+	Store       Store
+	MaxFailures int           // Неудач до блокировки
+	BaseDelay   time.Duration // Задержка после первой неудачи
+	MaxDelay    time.Duration // Верхняя граница экспоненциальной задержки
+	Lockout     time.Duration // Длительность блокировки
+	Now         func() time.Time
+}
+
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{
+		Store:       store,
+		MaxFailures: 5,
+		BaseDelay:   time.Second,
+		MaxDelay:    30 * time.Second,
+		Lockout:     15 * time.Minute,
+		Now:         time.Now,
+	}
+}
+
+func (l *Limiter) delay(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+	d := l.BaseDelay
+	for i := 1; i < failures && d < l.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > l.MaxDelay {
+		d = l.MaxDelay
+	}
+	return d
+}
+
+// Check возвращает *LimitError, если хотя бы для одного из ключей
+// попытка сейчас запрещена.
+func (l *Limiter) Check(keys ...string) error {
+	now := l.Now()
+	var worst *LimitError
+	for _, key := range keys {
+		rec, err := l.Store.Get(key)
+		if err != nil {
+			return fmt.Errorf("failed to read attempts for %s: %v", key, err)
+		}
+		var limit *LimitError
+		if now.Before(rec.LockedUntil) {
+			limit = &LimitError{Wait: rec.LockedUntil.Sub(now), Locked: true}
+		} else if next := rec.LastFailure.Add(l.delay(rec.Failures)); rec.Failures > 0 && now.Before(next) {
+			limit = &LimitError{Wait: next.Sub(now)}
+		}
+		if limit != nil && (worst == nil || limit.Wait > worst.Wait) {
+			worst = limit
+		}
+	}
+	if worst != nil {
+		return worst
+	}
+	return nil
+}
+
+// Fail записывает неудачную попытку для всех ключей.
+func (l *Limiter) Fail(keys ...string) error {
+	now := l.Now()
+	for _, key := range keys {
+		rec, err := l.Store.Get(key)
+		if err != nil {
+			return fmt.Errorf("failed to read attempts for %s: %v", key, err)
+		}
+		// После истёкшей блокировки или долгого перерыва между неудачами
+		// счёт начинается заново
+		if !rec.LockedUntil.IsZero() && !now.Before(rec.LockedUntil) ||
+			rec.LockedUntil.IsZero() && !rec.LastFailure.IsZero() && now.Sub(rec.LastFailure) >= l.Lockout {
+			rec = Record{}
+		}
+		rec.Failures++
+		rec.LastFailure = now
+		if rec.Failures >= l.MaxFailures {
+			rec.LockedUntil = now.Add(l.Lockout)
+		}
+		if err := l.Store.Put(key, rec); err != nil {
+			return fmt.Errorf("failed to save attempts for %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// Succeed сбрасывает счётчики после успешного входа.
+func (l *Limiter) Succeed(keys ...string) error {
+	for _, key := range keys {
+		if err := l.Store.Delete(key); err != nil {
+			return fmt.Errorf("failed to reset attempts for %s: %v", key, err)
+		}
+	}
+	return nil
+}
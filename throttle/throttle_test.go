@@ -0,0 +1,154 @@
+package throttle
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestLimiter возвращает ограничитель с хранилищем в памяти и часами,
+// которые двигает сам тест.
+func newTestLimiter() (*Limiter, *time.Time) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter(NewMemoryStore())
+	l.Now = func() time.Time { return now }
+	return l, &now
+}
+
+func limitError(t *testing.T, err error) *LimitError {
+	t.Helper()
+	var limit *LimitError
+	if !errors.As(err, &limit) {
+		t.Fatalf("expected *LimitError, got %v", err)
+	}
+	return limit
+}
+
+func TestBackoffGrows(t *testing.T) {
+	l, now := newTestLimiter()
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}
+	for i, d := range want {
+		if err := l.Fail("user:alice"); err != nil {
+			t.Fatal(err)
+		}
+		limit := limitError(t, l.Check("user:alice"))
+		if limit.Locked || limit.Wait != d {
+			t.Fatalf("after %d failures: wait %v locked %v, want %v", i+1, limit.Wait, limit.Locked, d)
+		}
+		*now = now.Add(d)
+		if err := l.Check("user:alice"); err != nil {
+			t.Fatalf("after waiting %v: %v", d, err)
+		}
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	l, _ := newTestLimiter()
+	l.MaxFailures = 100
+	for range 20 {
+		if err := l.Fail("user:alice"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if limit := limitError(t, l.Check("user:alice")); limit.Wait != l.MaxDelay {
+		t.Fatalf("wait %v, want %v", limit.Wait, l.MaxDelay)
+	}
+}
+
+func TestLockoutAfterMaxFailures(t *testing.T) {
+	l, now := newTestLimiter()
+	for i := 0; i < l.MaxFailures; i++ {
+		if err := l.Fail("user:alice"); err != nil {
+			t.Fatal(err)
+		}
+		*now = now.Add(l.MaxDelay)
+	}
+	limit := limitError(t, l.Check("user:alice"))
+	if !limit.Locked {
+		t.Fatalf("expected lockout after %d failures", l.MaxFailures)
+	}
+	if want := l.Lockout - l.MaxDelay; limit.Wait != want {
+		t.Fatalf("wait %v, want %v", limit.Wait, want)
+	}
+	// Другие ключи блокировка не задевает
+	if err := l.Check("user:bob"); err != nil {
+		t.Fatalf("unrelated key: %v", err)
+	}
+}
+
+func TestLockoutExpires(t *testing.T) {
+	l, now := newTestLimiter()
+	for range l.MaxFailures {
+		if err := l.Fail("user:alice"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	*now = now.Add(l.Lockout)
+	if err := l.Check("user:alice"); err != nil {
+		t.Fatalf("after lockout: %v", err)
+	}
+	// После блокировки счёт начинается заново, а не блокирует сразу
+	if err := l.Fail("user:alice"); err != nil {
+		t.Fatal(err)
+	}
+	if limit := limitError(t, l.Check("user:alice")); limit.Locked || limit.Wait != l.BaseDelay {
+		t.Fatalf("first failure after lockout: wait %v locked %v", limit.Wait, limit.Locked)
+	}
+}
+
+func TestSucceedResetsCounter(t *testing.T) {
+	l, _ := newTestLimiter()
+	for range l.MaxFailures - 1 {
+		if err := l.Fail("user:alice", "client:1.2.3.4"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Succeed("user:alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Check("user:alice"); err != nil {
+		t.Fatalf("after success: %v", err)
+	}
+	rec, err := l.Store.Get("user:alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Failures != 0 {
+		t.Fatalf("failures %d after success, want 0", rec.Failures)
+	}
+	// Ключ, который не сбрасывали, помнит неудачи
+	if err := l.Check("client:1.2.3.4"); err == nil {
+		t.Fatal("client key was reset too")
+	}
+}
+
+func TestCheckReportsLongestWait(t *testing.T) {
+	l, _ := newTestLimiter()
+	if err := l.Fail("user:alice"); err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if err := l.Fail("client:1.2.3.4"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if limit := limitError(t, l.Check("user:alice", "client:1.2.3.4")); limit.Wait != 4*time.Second {
+		t.Fatalf("wait %v, want 4s", limit.Wait)
+	}
+}
+
+func TestFailuresExpireWithoutLockout(t *testing.T) {
+	l, now := newTestLimiter()
+	for range l.MaxFailures - 1 {
+		if err := l.Fail("client:1.2.3.4"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	*now = now.Add(l.Lockout)
+	if err := l.Fail("client:1.2.3.4"); err != nil {
+		t.Fatal(err)
+	}
+	if limit := limitError(t, l.Check("client:1.2.3.4")); limit.Locked {
+		t.Fatal("old failures caused a lockout")
+	}
+}
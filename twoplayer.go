@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"egg_catcher2/api"
+	"egg_catcher2/sim"
+)
+
+// partnerPlayer — второй игрок в партии на двоих за одним компьютером.
+// Его сессия не сохраняется на диск и закрывается при выходе из режима.
+type partnerPlayer struct {
+	id     int
+	name   string
+	token  string
+	record int
+}
+
+// NewPartnerAuthState открывает вход второго игрока. Вместо «Remember me»
+// на экране выбирается режим: вместе или на счёт.
+func NewPartnerAuthState(backend api.Backend) *AuthState {
+	a := NewAuthState(backend)
+	a.partner = true
+	a.modeButton = a.rememberButton
+	a.modeButton.label = "Mode: Co-op"
+	return a
+}
+
+func (a *AuthState) toggleMode() {
+	a.versus = !a.versus
+	if a.versus {
+		a.modeButton.label = "Mode: Versus"
+	} else {
+		a.modeButton.label = "Mode: Co-op"
+	}
+}
+
+// startTwoPlayer начинает партию на двоих после входа второго игрока.
+func (g *Game) startTwoPlayer(session api.Session, versus bool) {
+	if session.Player.ID == g.playerID {
+		logOutPartner(session.Token)
+		g.statusMsg = "Player 2 must log in with another account"
+		return
+	}
+	*g = *NewGame(g.playerID, g.loseHeartPlayer, g.gainHeartPlayer, g.scoreHeartPlayer, g.bossMusic, g.bossHitEffect)
+	g.setPartner(&partnerPlayer{
+		id:     session.Player.ID,
+		name:   session.Player.Name,
+		token:  session.Token,
+		record: session.Player.HighScore,
+	}, versus)
+	log.Printf("Started two-player game with '%s' (versus: %v)", session.Player.Name, versus)
+}
+
+// setPartner переводит ещё не начатую партию в режим на двоих: первый
+// игрок управляет A/D, второй — стрелками, геймпады — по порядку. Второй
+// игрок берёт свой билет, и seed партии складывается из обоих.
+func (g *Game) setPartner(partner *partnerPlayer, versus bool) {
+	g.partner = partner
+	g.versus = versus
+	ticket := newTicket(partner.token)
+	seed := sim.CombineSeeds(g.Seed, ticket.Seed)
+	g.World = sim.NewMultiplayerWorld(seed, 2)
+	g.recording = sim.NewMultiplayerRecording(seed, 2)
+	g.setDifficulty(difficulty)
+	g.tickets = append(g.tickets[:1:1], ticket.Nonce)
+	g.input = newKeyboardInputSource(2)
+	g.twoPlayerButton.label = "One Player"
+}
+
+// endTwoPlayer выходит из аккаунта второго игрока; следующая партия
+// будет одиночной.
+func (g *Game) endTwoPlayer() {
+	logOutPartner(g.partner.token)
+	log.Printf("Player '%s' left the two-player game", g.partner.name)
+	g.partner = nil
+	g.twoPlayerButton.label = "Two Players"
+}
+
+func logOutPartner(token string) {
+	if backend == nil {
+		return
+	}
+	if err := backend.Logout(token); err != nil {
+		log.Printf("Error logging out player 2: %v", err)
+	}
+}
+
+// saveTwoPlayerData отправляет одну и ту же запись партии от имени обоих
+// игроков; сервер проверяет счёт каждого по его номеру в записи.
+func saveTwoPlayerData(g *Game) error {
+	replay := g.recording.Encode()
+	tokens := []string{currentSessionToken, g.partner.token}
+	var firstErr error
+	for slot, token := range tokens {
+		player, err := backend.SubmitGame(token, g.gameResult(slot, replay))
+		if err != nil {
+			log.Printf("Failed to save game data for player %d: %v", slot+1, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to save game data for player %d: %v", slot+1, err)
+			}
+			continue
+		}
+		if slot == 0 {
+			g.record = player.HighScore
+		} else {
+			g.partner.record = player.HighScore
+		}
+	}
+	return firstErr
+}
+
+// drawTwoPlayerResults выводит итоги обоих игроков на экран конца игры:
+// в режиме на счёт — победителя, в совместном — общий счёт.
+func (g *Game) drawTwoPlayerResults(screen *ebiten.Image) {
+	names := []string{"P1", "P2"}
+	records := map[int]int{0: g.record} // Рекорды известны только для своих аккаунтов
+	if g.playerName != "" {
+		names[0] = g.playerName
+	}
+	if g.partner != nil {
+		names[1] = g.partner.name
+		records[1] = g.partner.record
+	}
+	if g.online != nil {
+		copy(names, g.online.names)
+		records = map[int]int{g.online.slot: g.record}
+	}
+	for i := range names {
+		line := fmt.Sprintf("%s: %d, best combo %d", names[i], g.Wolves[i].Score, g.Wolves[i].BestCombo)
+		if record, ok := records[i]; ok && g.replay == nil {
+			line += fmt.Sprintf(" (record %d)", record)
+		}
+		ebitenutil.DebugPrintAt(screen, line, screenWidth/3-50, screenHeight/3-145+i*17)
+	}
+	var summary string
+	switch {
+	case !g.versus:
+		summary = fmt.Sprintf("Team Score: %d", g.TotalScore())
+	case g.Wolves[0].Score > g.Wolves[1].Score:
+		summary = "Winner: " + names[0]
+	case g.Wolves[1].Score > g.Wolves[0].Score:
+		summary = "Winner: " + names[1]
+	default:
+		summary = "Draw"
+	}
+	ebitenutil.DebugPrintAt(screen, summary, screenWidth/3-50, screenHeight/3-111)
+}